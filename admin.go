@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// maxAdminBodyBytes caps the request body the admin endpoints will accept
+	maxAdminBodyBytes = 64 << 10
+	// restartResponseGrace is how long past the restart budget /admin/restart
+	// still has to write its response
+	restartResponseGrace = 5 * time.Second
+)
+
+// requireToken rejects requests to the configured protected paths unless they
+// carry the admin token as a bearer token
+func (sm *ServiceManager) requireToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if sm.protectedPath(r.URL.Path) && !sm.authorized(r) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// protectedPath reports whether path needs the admin token
+func (sm *ServiceManager) protectedPath(path string) bool {
+	if path == "/health" || path == "/ready" {
+		return false
+	}
+	for _, protected := range sm.cfg().Server.ProtectedPaths {
+		if pathMatches(protected, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// pathMatches reports whether pattern covers path: exactly, or as a prefix when
+// pattern ends in / (so /admin/ matches /admin and /admin/restart)
+func pathMatches(pattern, path string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "/"); ok {
+		return path == prefix || strings.HasPrefix(path, pattern)
+	}
+	return path == pattern
+}
+
+// requireAdmin rejects the request unless it uses the given method and carries
+// the admin token. It reports whether the handler should continue.
+func (sm *ServiceManager) requireAdmin(w http.ResponseWriter, r *http.Request, method string) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, maxAdminBodyBytes)
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return false
+	}
+	if !sm.authorized(r) {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		return false
+	}
+	return true
+}
+
+// dbPauseHandler suspends database monitoring and reconnection for maintenance
+func (sm *ServiceManager) dbPauseHandler(w http.ResponseWriter, r *http.Request) {
+	if !sm.requireAdmin(w, r, http.MethodPost) {
+		return
+	}
+
+	if !sm.dbPaused.Swap(true) {
+		sm.logger.Info("Database monitoring paused for maintenance")
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"database_monitor": "paused"})
+}
+
+// dbResumeHandler resumes database monitoring after maintenance
+func (sm *ServiceManager) dbResumeHandler(w http.ResponseWriter, r *http.Request) {
+	if !sm.requireAdmin(w, r, http.MethodPost) {
+		return
+	}
+
+	if sm.dbPaused.Swap(false) {
+		sm.logger.Info("Database monitoring resumed")
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"database_monitor": "running"})
+}
+
+// restartHandler restarts the Python workers one at a time and answers once every
+// replacement is ready
+func (sm *ServiceManager) restartHandler(w http.ResponseWriter, r *http.Request) {
+	if !sm.requireAdmin(w, r, http.MethodPost) {
+		return
+	}
+
+	// A rolling restart can outlast the server's write timeout, so this response
+	// gets a deadline covering every worker's stop and ready timeouts instead
+	cfg := sm.cfg().Server
+	budget := time.Duration(len(sm.workers)) * (cfg.ShutdownTimeout + cfg.ReadyTimeout)
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(budget + restartResponseGrace)); err != nil {
+		sm.logger.Warnf("Failed to extend the restart response deadline: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), budget)
+	defer cancel()
+
+	if err := sm.restartWorkers(ctx); err != nil {
+		sm.logger.Errorf("Python restart failed: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"restarted": len(sm.workers)})
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// slowScript takes a while to report ready, then serves 200 on every path
+const slowScript = `
+import http.server, os, time
+class Handler(http.server.BaseHTTPRequestHandler):
+    def do_GET(self):
+        self.send_response(200)
+        self.end_headers()
+    def log_message(self, *args):
+        pass
+server = http.server.HTTPServer(("127.0.0.1", int(os.environ["PORT"])), Handler)
+time.sleep(0.4)
+print("READY", flush=True)
+server.serve_forever()
+`
+
+func TestRestartOutlastsWriteTimeout(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "health.sock")
+	sm := newTestManager(t, fmt.Sprintf(`server:
+  port: "%s"
+  workers: 2
+  script_path: %s
+  ready_log_pattern: READY
+  health_socket: %s
+  admin_token: secret
+  write_timeout: 300ms
+`, freePort(t), writeScript(t, slowScript), socket))
+	if err := sm.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer func() {
+		sm.cancel()
+		sm.Wait()
+	}()
+	for _, w := range sm.workers {
+		waitFor(t, 10*time.Second, w.label+" to become ready", w.isReady)
+	}
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+		},
+	}}
+	req, _ := http.NewRequest(http.MethodPost, "http://health/admin/restart", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+
+	// Restarting both workers takes well over the 300ms write timeout
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("restart request failed after %s: %v", time.Since(start), err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Restarted int `json:"restarted"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to read restart response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || body.Restarted != 2 {
+		t.Errorf("restart answered %s with %d restarted, want 200 with 2", resp.Status, body.Restarted)
+	}
+	if elapsed := time.Since(start); elapsed < 800*time.Millisecond {
+		t.Errorf("restart answered after %s, before both workers could be ready again", elapsed)
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// preflightCheck is one dependency verified by -check
+type preflightCheck struct {
+	name string
+	run  func() (string, error)
+}
+
+// preflight verifies that the interpreter, script and database are usable without
+// starting any services, writes a summary to out and reports whether all passed
+func (sm *ServiceManager) preflight(out io.Writer) bool {
+	checks := []preflightCheck{
+		{"python", sm.checkInterpreter},
+		{"script", sm.checkScript},
+		{"database", sm.checkDatabase},
+	}
+
+	passed := true
+	for _, check := range checks {
+		detail, err := check.run()
+		if err != nil {
+			passed = false
+			fmt.Fprintf(out, "FAIL  %-8s  %v\n", check.name, err)
+			continue
+		}
+		fmt.Fprintf(out, "ok    %-8s  %s\n", check.name, detail)
+	}
+	return passed
+}
+
+// checkInterpreter confirms the Python interpreter can be found
+func (sm *ServiceManager) checkInterpreter() (string, error) {
+	cfg := sm.cfg()
+	if cfg.Server.ExecMode == "direct" {
+		return "not used in exec_mode direct", nil
+	}
+
+	// Resolve on a copy and swap it in, since the health server may already be
+	// reading the shared config
+	resolved := *cfg
+	if err := sm.resolvePythonInterpreter(&resolved); err != nil {
+		return "", err
+	}
+	if resolved.Server.PythonPath != cfg.Server.PythonPath {
+		sm.configMu.Lock()
+		next := *sm.config
+		next.Server.PythonPath = resolved.Server.PythonPath
+		sm.config = &next
+		sm.configMu.Unlock()
+	}
+
+	path, err := exec.LookPath(resolved.Server.PythonPath)
+	if err != nil {
+		return "", fmt.Errorf("interpreter %q not found: %w", resolved.Server.PythonPath, err)
+	}
+	return path, nil
+}
+
+// logPythonVersion records which Python the workers will run
+func (sm *ServiceManager) logPythonVersion(path string) {
+	ctx, cancel := context.WithTimeout(sm.ctx, 5*time.Second)
+	defer cancel()
+
+	// Python 2 prints its version to stderr
+	out, err := exec.CommandContext(ctx, path, "--version").CombinedOutput()
+	if err != nil {
+		sm.logger.Warnf("Failed to get Python version from %s: %v", path, err)
+		return
+	}
+	sm.logger.Infof("Using %s (%s)", strings.TrimSpace(string(out)), path)
+}
+
+// checkScript confirms the Python script exists, and is executable in direct mode
+func (sm *ServiceManager) checkScript() (string, error) {
+	cfg := sm.cfg()
+	script := scriptPath(cfg)
+	info, err := os.Stat(script)
+	if err != nil {
+		return "", err
+	}
+	if cfg.Server.ExecMode == "direct" && info.Mode().Perm()&0o111 == 0 {
+		return "", fmt.Errorf("%s is not executable, required by exec_mode direct", script)
+	}
+	return script, nil
+}
+
+// checkDatabase confirms the database accepts connections and passes the health check
+func (sm *ServiceManager) checkDatabase() (string, error) {
+	if err := sm.initDatabase(context.Background()); err != nil {
+		return "", err
+	}
+	defer sm.database().Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	latency, err := sm.pingDatabase(ctx)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s reachable in %s", sm.cfg().Database.Driver, latency.Round(time.Microsecond)), nil
+}
+
+// runOnce runs a single health check without starting any services, writes the
+// report to out as JSON and reports whether the service is healthy or degraded. Log output
+// goes to stderr so out only holds the report.
+func (sm *ServiceManager) runOnce(out io.Writer) bool {
+	sm.logger.Base().SetOutput(os.Stderr)
+	sm.startedAt = time.Now()
+
+	// An unreachable database is part of the report rather than a failure here
+	if err := sm.initDatabase(context.Background()); err != nil {
+		sm.logger.Errorf("Failed to connect to database: %v", err)
+	}
+	if sm.cfg().Database.ReplicaURL != "" {
+		if err := sm.initReplica(context.Background()); err != nil {
+			sm.logger.Errorf("Failed to open replica database: %v", err)
+		}
+	}
+	defer sm.closeDatabase()
+
+	report := sm.checkAll(context.Background(), true)
+	body, err := renderHealth(report, healthSchemaVersion)
+	if err == nil {
+		body, err = remapFields(body, sm.cfg().Server.HealthFieldMap)
+	}
+	if err != nil {
+		sm.logger.Errorf("Failed to render health report: %v", err)
+		return false
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(body); err != nil {
+		sm.logger.Errorf("Failed to write health report: %v", err)
+		return false
+	}
+	return report.Status != "unhealthy"
+}
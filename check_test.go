@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"sync"
+	"testing"
+)
+
+// statusRows creates the status table in sm's SQLite database and returns a
+// function counting its rows
+func statusRows(t *testing.T, sm *ServiceManager) func() int {
+	t.Helper()
+
+	updateConfig(sm, func(cfg *Config) { cfg.Database.StatusTable = "service_status" })
+	db, err := sql.Open("sqlite", sm.cfg().Database.DBName)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.Exec("CREATE TABLE service_status (recorded_at TIMESTAMP, component TEXT, status TEXT)"); err != nil {
+		t.Fatalf("failed to create status table: %v", err)
+	}
+
+	return func() int {
+		var rows int
+		if err := db.QueryRow("SELECT COUNT(*) FROM service_status").Scan(&rows); err != nil {
+			t.Fatalf("failed to count status rows: %v", err)
+		}
+		return rows
+	}
+}
+
+func TestCheckDoesNotRecordStatus(t *testing.T) {
+	sm := newTestManager(t, "")
+	rows := statusRows(t, sm)
+
+	if _, err := sm.checkDatabase(); err != nil {
+		t.Fatalf("checkDatabase: %v", err)
+	}
+	if n := rows(); n != 0 {
+		t.Errorf("-check wrote %d status rows, want none", n)
+	}
+
+	// A running manager does record the database coming up
+	sm.history.enable()
+	if err := sm.initDatabase(context.Background()); err != nil {
+		t.Fatalf("initDatabase: %v", err)
+	}
+	defer sm.closeDatabase()
+	if n := rows(); n != 1 {
+		t.Errorf("running manager wrote %d status rows, want 1", n)
+	}
+}
+
+func TestCheckInterpreterSwapsResolvedConfig(t *testing.T) {
+	want, err := exec.LookPath("python3")
+	if err != nil {
+		t.Skip("python3 not found")
+	}
+	sm := newTestManager(t, "server:\n  python_candidates: [no-such-python, python3]\n")
+	before := sm.cfg()
+
+	// /config reads the shared config while the interpreter is resolved
+	done := make(chan struct{})
+	var readers sync.WaitGroup
+	readers.Add(1)
+	go func() {
+		defer readers.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				sm.configHandler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/config", nil))
+			}
+		}
+	}()
+
+	path, err := sm.checkInterpreter()
+	close(done)
+	readers.Wait()
+	if err != nil {
+		t.Fatalf("checkInterpreter: %v", err)
+	}
+	if path != want {
+		t.Errorf("interpreter = %s, want %s", path, want)
+	}
+	if got := sm.cfg().Server.PythonPath; got != want {
+		t.Errorf("python_path = %s after resolving, want %s", got, want)
+	}
+	if before.Server.PythonPath == want {
+		t.Error("resolving changed the config in place instead of swapping in a copy")
+	}
+}
+
+func TestOnceDoesNotRecordStatus(t *testing.T) {
+	sm := newTestManager(t, fmt.Sprintf("server:\n  port: \"%s\"\n", freePort(t)))
+	rows := statusRows(t, sm)
+
+	sm.runOnce(io.Discard)
+	if n := rows(); n != 0 {
+		t.Errorf("-once wrote %d status rows, want none", n)
+	}
+}
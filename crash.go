@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// crashReport is the post-mortem record written when the Python server crashes
+type crashReport struct {
+	Timestamp time.Time `json:"timestamp"`
+	Worker    int       `json:"worker"`
+	ExitCode  int       `json:"exit_code"`
+	Error     string    `json:"error"`
+	Stdout    []string  `json:"stdout"`
+	Stderr    []string  `json:"stderr"`
+}
+
+// writeCrashReport saves a crash report to the crash directory and prunes old reports
+func (sm *ServiceManager) writeCrashReport(report crashReport) error {
+	dir := sm.cfg().Server.CrashDir
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create crash directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal crash report: %w", err)
+	}
+
+	name := fmt.Sprintf("crash-%s.json", report.Timestamp.UTC().Format("20060102T150405.000000000Z"))
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write crash report: %w", err)
+	}
+	sm.logger.Infof("Crash report written to %s", path)
+
+	return sm.pruneCrashReports()
+}
+
+// pruneCrashReports removes the oldest crash reports beyond the configured retention
+func (sm *ServiceManager) pruneCrashReports() error {
+	matches, err := filepath.Glob(filepath.Join(sm.cfg().Server.CrashDir, "crash-*.json"))
+	if err != nil {
+		return fmt.Errorf("failed to list crash reports: %w", err)
+	}
+
+	// Names embed a sortable timestamp, so lexical order is chronological
+	sort.Strings(matches)
+	for len(matches) > sm.cfg().Server.CrashRetention {
+		if err := os.Remove(matches[0]); err != nil {
+			return fmt.Errorf("failed to remove old crash report: %w", err)
+		}
+		matches = matches[1:]
+	}
+	return nil
+}
+
+// crashState is the restart policy's state file: recent crash times per worker
+type crashState struct {
+	Workers map[int][]time.Time `json:"workers"`
+}
+
+// pruneCrashes drops crashes older than window, keeping the rest in order
+func pruneCrashes(crashes []time.Time, now time.Time, window time.Duration) []time.Time {
+	for len(crashes) > 0 && now.Sub(crashes[0]) > window {
+		crashes = crashes[1:]
+	}
+	return crashes
+}
+
+// loadCrashes returns the crashes within window recorded for a worker in the
+// state file, if one is configured
+func (sm *ServiceManager) loadCrashes(index int, window time.Duration) []time.Time {
+	path := sm.cfg().Server.RestartPolicy.StateFile
+	if path == "" {
+		return nil
+	}
+
+	sm.crashStateMu.Lock()
+	defer sm.crashStateMu.Unlock()
+
+	state, err := readCrashState(path)
+	if err != nil {
+		sm.logger.Warnf("Ignoring restart state: %v", err)
+		return nil
+	}
+	return pruneCrashes(state.Workers[index], time.Now(), window)
+}
+
+// saveCrashes records a worker's recent crashes in the state file, if one is
+// configured. Failures are logged; they never stop a restart.
+func (sm *ServiceManager) saveCrashes(index int, crashes []time.Time) {
+	path := sm.cfg().Server.RestartPolicy.StateFile
+	if path == "" {
+		return
+	}
+
+	sm.crashStateMu.Lock()
+	defer sm.crashStateMu.Unlock()
+
+	state, err := readCrashState(path)
+	if err != nil {
+		sm.logger.Warnf("Replacing unreadable restart state: %v", err)
+		state = crashState{Workers: map[int][]time.Time{}}
+	}
+	state.Workers[index] = crashes
+	if err := writeCrashState(path, state); err != nil {
+		sm.logger.Warnf("Failed to save restart state: %v", err)
+	}
+}
+
+// readCrashState reads the state file. A missing file is an empty state.
+func readCrashState(path string) (crashState, error) {
+	state := crashState{Workers: map[int][]time.Time{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	} else if err != nil {
+		return state, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return crashState{Workers: map[int][]time.Time{}}, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if state.Workers == nil {
+		state.Workers = map[int][]time.Time{}
+	}
+	return state, nil
+}
+
+// writeCrashState replaces the state file atomically, so a manager killed
+// mid-write leaves the previous state behind
+func writeCrashState(path string, state crashState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to marshal restart state: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create restart state directory: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write restart state: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to replace restart state: %w", err)
+	}
+	return nil
+}
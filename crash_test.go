@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCrashStatePersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "restarts.json")
+	sm := newTestManager(t, "server:\n  restart_policy:\n    state_file: "+path+"\n")
+
+	now := time.Now()
+	sm.saveCrashes(0, []time.Time{now.Add(-time.Hour), now.Add(-time.Minute), now})
+	sm.saveCrashes(1, []time.Time{now})
+
+	// A fresh manager sees only the crashes still within the window
+	restarted := newTestManager(t, "server:\n  restart_policy:\n    state_file: "+path+"\n")
+	if got := restarted.loadCrashes(0, 5*time.Minute); len(got) != 2 || !got[1].Equal(now) {
+		t.Errorf("worker 0 crashes = %v, want the last two", got)
+	}
+	if got := restarted.loadCrashes(1, 5*time.Minute); len(got) != 1 {
+		t.Errorf("worker 1 crashes = %v, want one", got)
+	}
+	if got := restarted.loadCrashes(2, 5*time.Minute); len(got) != 0 {
+		t.Errorf("worker 2 crashes = %v, want none", got)
+	}
+}
+
+func TestCrashStateDisabled(t *testing.T) {
+	sm := newTestManager(t, "")
+	sm.saveCrashes(0, []time.Time{time.Now()})
+	if got := sm.loadCrashes(0, time.Hour); got != nil {
+		t.Errorf("crashes = %v without a state file, want none", got)
+	}
+}
+
+func TestNegativeCrashRetentionRejected(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	data := "server:\n  crash_retention: -1\ndatabase:\n  driver: sqlite\n  db_name: test.db\n"
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	_, err := loadConfig(path)
+	if err == nil || !strings.Contains(err.Error(), "server.crash_retention: -1 must not be negative") {
+		t.Errorf("loadConfig error = %v, want crash_retention rejected", err)
+	}
+}
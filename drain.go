@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// drain prepares for shutdown by reporting not ready, telling the workers to drain
+// and waiting DrainDelay so load balancers can move traffic away before Python is
+// stopped. Another shutdown signal cuts the wait short.
+func (sm *ServiceManager) drain() {
+	cfg := sm.cfg()
+	if cfg.Server.DrainDelay <= 0 && cfg.Server.DrainPath == "" {
+		return
+	}
+
+	sm.draining.Store(true)
+	sm.logger.Infof("Draining for %s before stopping Python...", cfg.Server.DrainDelay)
+
+	if cfg.Server.DrainPath != "" {
+		for _, w := range sm.workers {
+			if err := sm.requestDrain(w); err != nil {
+				sm.logger.Warnf("Failed to ask %s to drain: %v", w.label, err)
+			}
+		}
+	}
+
+	timer := time.NewTimer(cfg.Server.DrainDelay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		sm.logger.Info("Drain finished")
+	case sig := <-sm.shutdown:
+		sm.logger.Warnf("Shutdown signal %s received again, skipping the rest of the drain", sig)
+	case <-sm.ctx.Done():
+	}
+}
+
+// requestDrain sends a POST to a worker's drain endpoint
+func (sm *ServiceManager) requestDrain(w *pythonWorker) error {
+	cfg := sm.cfg()
+	ctx, cancel := context.WithTimeout(sm.ctx, cfg.Server.PythonHealthTimeout)
+	defer cancel()
+
+	url := "http://" + net.JoinHostPort(cfg.Server.PythonHealthHost, w.port) + cfg.Server.DrainPath
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"syscall"
+	"testing"
+	"time"
+)
+
+// drainFor runs drain with the given delay and returns a channel closed once it returns
+func drainFor(t *testing.T, delay time.Duration) (*ServiceManager, <-chan struct{}) {
+	t.Helper()
+
+	sm := newTestManager(t, "")
+	updateConfig(sm, func(config *Config) { config.Server.DrainDelay = delay })
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		sm.drain()
+	}()
+	waitFor(t, time.Second, "the drain to start", sm.draining.Load)
+	return sm, done
+}
+
+func TestDrainInterruptedBySignal(t *testing.T) {
+	sm, done := drainFor(t, time.Minute)
+
+	start := time.Now()
+	sm.shutdown <- syscall.SIGTERM
+	select {
+	case <-done:
+		if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+			t.Errorf("drain took %s to stop after the second signal", elapsed)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("drain kept waiting after a second shutdown signal")
+	}
+}
+
+func TestDrainInterruptedByCancel(t *testing.T) {
+	sm, done := drainFor(t, time.Minute)
+
+	sm.cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("drain kept waiting after the manager was cancelled")
+	}
+}
+
+func TestDrainWaitsForDelay(t *testing.T) {
+	start := time.Now()
+	_, done := drainFor(t, 200*time.Millisecond)
+
+	<-done
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("drain finished after %s, want the 200ms delay", elapsed)
+	}
+}
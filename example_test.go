@@ -0,0 +1,32 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExampleConfigLoads(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "friend-finder.yml")
+	if err := writeExampleConfig(path, false); err != nil {
+		t.Fatalf("writeExampleConfig: %v", err)
+	}
+	if _, err := loadConfig(path); err != nil {
+		t.Errorf("example config does not load: %v", err)
+	}
+}
+
+func TestExampleConfigDocumentsEveryKey(t *testing.T) {
+	// A key, set or commented out, is documented by the comment line above it.
+	// Top-level sections and the entries of a map such as env are not keys.
+	previous := ""
+	for i, line := range strings.Split(string(exampleConfig), "\n") {
+		entry := strings.TrimPrefix(strings.TrimSpace(line), "# ")
+		name, _, isKey := strings.Cut(entry, ":")
+		isKey = isKey && strings.HasPrefix(line, "  ") && !strings.ContainsAny(name, " \"")
+		if isKey && !strings.HasSuffix(previous, ":") && !strings.Contains(previous, "default") {
+			t.Errorf("line %d: %s has no comment giving its default", i+1, name)
+		}
+		previous = entry
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// healthSchemaVersion is the current health response schema version.
+// Fields may be added within a version; removing or changing one requires a new version.
+const healthSchemaVersion = 1
+
+// healthStderrLines is how many recent stderr lines per unhealthy worker are
+// included in the health response
+const healthStderrLines = 10
+
+// latencySamples is how many recent samples the rolling latency p95 covers
+const latencySamples = 100
+
+// healthReport is the result of a health check
+type healthReport struct {
+	SchemaVersion  int     `json:"schema_version"`
+	Status         string  `json:"status"`
+	Database       bool    `json:"database"`
+	DatabaseStatus string  `json:"database_status"` // "ok", "unavailable", or "maintenance"
+	DatabaseMillis float64 `json:"database_latency_ms"`
+	PythonServer   bool    `json:"python_server"`
+	// PythonMillis is how long the slowest worker took to answer this check. The
+	// p95 fields cover recent checks, including those of the monitors.
+	PythonMillis   float64 `json:"python_latency_ms"`
+	DatabaseP95    float64 `json:"database_latency_p95_ms"`
+	PythonP95      float64 `json:"python_latency_p95_ms"`
+	Workers        int     `json:"workers"`
+	HealthyWorkers int     `json:"healthy_workers"`
+	// PythonStderr holds the last lines of stderr from unhealthy workers
+	PythonStderr []string `json:"python_stderr,omitempty"`
+	// UptimeSeconds is how long the manager has been running. PythonRestarts counts
+	// crash restarts and DBReconnects successful database reconnections since then.
+	UptimeSeconds  int64 `json:"uptime_seconds"`
+	PythonRestarts int   `json:"python_restarts"`
+	DBReconnects   int   `json:"db_reconnects"`
+
+	// Replica is only reported when a read replica is configured
+	Replica *replicaReport `json:"replica,omitempty"`
+	// Degraded lists why Status is "degraded": the service works, but a restart is
+	// in progress, the replica is down or a check was slow
+	Degraded []string `json:"degraded_reasons,omitempty"`
+}
+
+// flatHealthReport is schema version 0, the original /health response without a
+// schema_version, kept for monitors written against it
+type flatHealthReport struct {
+	Status       string `json:"status"` // "healthy" or "unhealthy"
+	Database     bool   `json:"database"`
+	PythonServer bool   `json:"python_server"`
+}
+
+// countPythonRestart records a Python restart after a crash
+func (sm *ServiceManager) countPythonRestart() {
+	sm.statsMu.Lock()
+	defer sm.statsMu.Unlock()
+	sm.pythonRestarts++
+}
+
+// countDBReconnect records a successful database reconnection
+func (sm *ServiceManager) countDBReconnect() {
+	sm.statsMu.Lock()
+	defer sm.statsMu.Unlock()
+	sm.dbReconnects++
+}
+
+// restartCounts returns the Python restart and database reconnection counts
+func (sm *ServiceManager) restartCounts() (pythonRestarts, dbReconnects int) {
+	sm.statsMu.Lock()
+	defer sm.statsMu.Unlock()
+	return sm.pythonRestarts, sm.dbReconnects
+}
+
+// latencyWindow keeps the most recent latencySamples durations
+type latencyWindow struct {
+	mu      sync.Mutex
+	samples []time.Duration
+	next    int
+}
+
+// add records a sample, replacing the oldest once the window is full
+func (l *latencyWindow) add(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.samples) < latencySamples {
+		l.samples = append(l.samples, d)
+		return
+	}
+	l.samples[l.next] = d
+	l.next = (l.next + 1) % latencySamples
+}
+
+// p95 returns the 95th percentile of the recorded samples, or 0 without any
+func (l *latencyWindow) p95() time.Duration {
+	l.mu.Lock()
+	sorted := slices.Clone(l.samples)
+	l.mu.Unlock()
+
+	if len(sorted) == 0 {
+		return 0
+	}
+	slices.Sort(sorted)
+	return sorted[(len(sorted)*95+99)/100-1]
+}
+
+// millis converts a duration to fractional milliseconds for JSON output
+func millis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// requestedSchemaVersion returns the schema version requested via ?schema=N or
+// an Accept-Version header, the query parameter taking precedence, defaulting to
+// the current version. N may be written as vN.
+func requestedSchemaVersion(r *http.Request) (int, error) {
+	raw := r.URL.Query().Get("schema")
+	if raw == "" {
+		raw = strings.TrimSpace(r.Header.Get("Accept-Version"))
+	}
+	if raw == "" {
+		return healthSchemaVersion, nil
+	}
+
+	version, err := strconv.Atoi(strings.TrimPrefix(raw, "v"))
+	if err != nil {
+		return 0, fmt.Errorf("invalid schema version: %q", raw)
+	}
+	return version, nil
+}
+
+// renderHealth shapes a health report for the requested schema version
+func renderHealth(report healthReport, version int) (any, error) {
+	switch version {
+	case 0:
+		// The flat format predates "degraded", which still means the service works
+		status := report.Status
+		if status == "degraded" {
+			status = "healthy"
+		}
+		return flatHealthReport{
+			Status:       status,
+			Database:     report.Database,
+			PythonServer: report.PythonServer,
+		}, nil
+	case 1:
+		report.SchemaVersion = 1
+		return report, nil
+	default:
+		return nil, fmt.Errorf("unsupported schema version: %d", version)
+	}
+}
+
+// remapFields renames the top-level JSON fields of body according to fieldMap,
+// leaving fields without a mapping unchanged
+func remapFields(body any, fieldMap map[string]string) (any, error) {
+	if len(fieldMap) == 0 {
+		return body, nil
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal health report: %w", err)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("failed to remap health report: %w", err)
+	}
+
+	remapped := make(map[string]json.RawMessage, len(fields))
+	for name, value := range fields {
+		if mapped, ok := fieldMap[name]; ok {
+			name = mapped
+		}
+		remapped[name] = value
+	}
+	return remapped, nil
+}
+
+// writeJSON writes v as a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, statusCode int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(v)
+}
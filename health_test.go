@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestRequestedSchemaVersion(t *testing.T) {
+	for _, tc := range []struct {
+		target, header string
+		want           int
+	}{
+		{"/health", "", healthSchemaVersion},
+		{"/health?schema=0", "", 0},
+		{"/health?schema=v0", "", 0},
+		{"/health", "0", 0},
+		{"/health", "v1", 1},
+		{"/health?schema=1", "0", 1},
+	} {
+		r := httptest.NewRequest(http.MethodGet, tc.target, nil)
+		if tc.header != "" {
+			r.Header.Set("Accept-Version", tc.header)
+		}
+		got, err := requestedSchemaVersion(r)
+		if err != nil {
+			t.Fatalf("%s with Accept-Version %q: %v", tc.target, tc.header, err)
+		}
+		if got != tc.want {
+			t.Errorf("%s with Accept-Version %q: got version %d, want %d", tc.target, tc.header, got, tc.want)
+		}
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/health?schema=latest", nil)
+	if _, err := requestedSchemaVersion(r); err == nil {
+		t.Error("expected an error for a non-numeric schema version")
+	}
+}
+
+func TestRenderHealthFlatFormat(t *testing.T) {
+	report := healthReport{
+		Status:       "degraded",
+		Database:     true,
+		PythonServer: true,
+		Workers:      2,
+		Degraded:     []string{"worker 1 is restarting"},
+	}
+
+	body, err := renderHealth(report, 0)
+	if err != nil {
+		t.Fatalf("renderHealth: %v", err)
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatalf("failed to unmarshal %s: %v", data, err)
+	}
+	var names []string
+	for name := range fields {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	if want := []string{"database", "python_server", "status"}; !slices.Equal(names, want) {
+		t.Errorf("flat format has fields %v, want %v", names, want)
+	}
+	if fields["status"] != "healthy" {
+		t.Errorf("degraded report rendered as %v in the flat format, want healthy", fields["status"])
+	}
+
+	if _, err := renderHealth(report, healthSchemaVersion+1); err == nil {
+		t.Error("expected an error for an unsupported schema version")
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// logLevel is the severity of a log message
+type logLevel int32
+
+const (
+	levelDebug logLevel = iota
+	levelInfo
+	levelWarn
+	levelError
+)
+
+func (l logLevel) String() string {
+	switch l {
+	case levelDebug:
+		return "DEBUG"
+	case levelInfo:
+		return "INFO"
+	case levelWarn:
+		return "WARN"
+	default:
+		return "ERROR"
+	}
+}
+
+// parseLogLevel converts a configured level name to a logLevel
+func parseLogLevel(name string) (logLevel, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "debug":
+		return levelDebug, nil
+	case "info", "":
+		return levelInfo, nil
+	case "warn", "warning":
+		return levelWarn, nil
+	case "error":
+		return levelError, nil
+	default:
+		return 0, fmt.Errorf("unknown log level: %s", name)
+	}
+}
+
+// logRecord is a single log line in JSON format
+type logRecord struct {
+	Timestamp string `json:"timestamp"`
+	Level     string `json:"level"`
+	Component string `json:"component"`
+	Stream    string `json:"stream,omitempty"`
+	Message   string `json:"message"`
+}
+
+// formatJSONRecord renders a log message as a JSON object
+func formatJSONRecord(level logLevel, component, stream, message string) string {
+	data, err := json.Marshal(logRecord{
+		Timestamp: time.Now().Format(time.RFC3339Nano),
+		Level:     level.String(),
+		Component: component,
+		Stream:    stream,
+		Message:   message,
+	})
+	if err != nil {
+		return message
+	}
+	return string(data)
+}
+
+// leveledLogger wraps a log.Logger and drops messages below its level.
+// The level can be changed while the logger is in use. In JSON mode each
+// message is written as a logRecord instead of prefixed text.
+type leveledLogger struct {
+	out       *log.Logger
+	level     atomic.Int32
+	json      bool
+	component string
+}
+
+func newLeveledLogger(w io.Writer, prefix string, flag int, level logLevel, format string) *leveledLogger {
+	l := &leveledLogger{out: log.New(w, prefix, flag)}
+	if format == "json" {
+		l.out = log.New(w, "", 0)
+		l.json = true
+		l.component = strings.ToLower(strings.Trim(prefix, "[] "))
+	}
+	l.SetLevel(level)
+	return l
+}
+
+// JSON reports whether the logger writes JSON records
+func (l *leveledLogger) JSON() bool {
+	return l.json
+}
+
+// SetLevel changes the minimum level that is logged
+func (l *leveledLogger) SetLevel(level logLevel) {
+	l.level.Store(int32(level))
+}
+
+// Enabled reports whether messages at level are logged
+func (l *leveledLogger) Enabled(level logLevel) bool {
+	return level >= logLevel(l.level.Load())
+}
+
+// Base returns the underlying logger, which ignores levels
+func (l *leveledLogger) Base() *log.Logger {
+	return l.out
+}
+
+func (l *leveledLogger) Debugf(format string, v ...any) { l.logf(levelDebug, format, v...) }
+func (l *leveledLogger) Infof(format string, v ...any)  { l.logf(levelInfo, format, v...) }
+func (l *leveledLogger) Warnf(format string, v ...any)  { l.logf(levelWarn, format, v...) }
+func (l *leveledLogger) Errorf(format string, v ...any) { l.logf(levelError, format, v...) }
+
+func (l *leveledLogger) Debug(v ...any) { l.log(levelDebug, v...) }
+func (l *leveledLogger) Info(v ...any)  { l.log(levelInfo, v...) }
+func (l *leveledLogger) Warn(v ...any)  { l.log(levelWarn, v...) }
+func (l *leveledLogger) Error(v ...any) { l.log(levelError, v...) }
+
+// logf and log are called through exactly one wrapper method and call output,
+// so a call depth of 4 attributes Lshortfile output to the original caller
+func (l *leveledLogger) logf(level logLevel, format string, v ...any) {
+	if l.Enabled(level) {
+		l.output(level, fmt.Sprintf(format, v...))
+	}
+}
+
+func (l *leveledLogger) log(level logLevel, v ...any) {
+	if l.Enabled(level) {
+		l.output(level, strings.TrimSuffix(fmt.Sprintln(v...), "\n"))
+	}
+}
+
+func (l *leveledLogger) output(level logLevel, message string) {
+	if l.json {
+		l.out.Output(4, formatJSONRecord(level, l.component, "", message))
+		return
+	}
+	l.out.Output(4, level.String()+" "+message)
+}
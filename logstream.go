@@ -0,0 +1,204 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/websocket"
+)
+
+// logSubscriberBuffer is how many lines a slow subscriber may fall behind before
+// its oldest lines are dropped
+const logSubscriberBuffer = 256
+
+// errTooManySubscribers is returned by subscribe once maxSubscribers are connected
+var errTooManySubscribers = errors.New("too many log stream clients")
+
+// logBroadcaster fans out Python output lines to live subscribers and keeps the
+// most recent lines for replay to new ones
+type logBroadcaster struct {
+	mu             sync.Mutex
+	subscribers    map[chan string]struct{}
+	maxSubscribers int
+	recent         *lineRing
+}
+
+// newLogBroadcaster creates a broadcaster that replays up to replay lines and
+// allows up to maxSubscribers concurrent subscribers; zero means unlimited
+func newLogBroadcaster(replay, maxSubscribers int) *logBroadcaster {
+	lb := &logBroadcaster{
+		subscribers:    make(map[chan string]struct{}),
+		maxSubscribers: maxSubscribers,
+	}
+	if replay > 0 {
+		lb.recent = newLineRing(replay)
+	}
+	return lb
+}
+
+// subscribe registers a new subscriber and returns the recent lines to replay
+// and its channel for the lines that follow them
+func (lb *logBroadcaster) subscribe() ([]string, chan string, error) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	if lb.maxSubscribers > 0 && len(lb.subscribers) >= lb.maxSubscribers {
+		return nil, nil, errTooManySubscribers
+	}
+
+	var replay []string
+	if lb.recent != nil {
+		replay = lb.recent.snapshot()
+	}
+	ch := make(chan string, logSubscriberBuffer)
+	lb.subscribers[ch] = struct{}{}
+	return replay, ch, nil
+}
+
+// unsubscribe removes a subscriber
+func (lb *logBroadcaster) unsubscribe(ch chan string) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	delete(lb.subscribers, ch)
+}
+
+// publish sends a line to every subscriber, dropping a subscriber's oldest
+// line when it has fallen behind so publishing never blocks
+func (lb *logBroadcaster) publish(line string) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	if lb.recent != nil {
+		lb.recent.add(line)
+	}
+
+	for ch := range lb.subscribers {
+		select {
+		case ch <- line:
+			continue
+		default:
+		}
+
+		// Buffer is full, drop the oldest line and retry once
+		select {
+		case <-ch:
+		default:
+		}
+		select {
+		case ch <- line:
+		default:
+		}
+	}
+}
+
+var logUpgrader = websocket.Upgrader{
+	// Access is controlled by the admin token rather than the origin
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// logsWebSocketHandler streams Python stdout/stderr lines to a WebSocket client,
+// starting with the most recent lines
+func (sm *ServiceManager) logsWebSocketHandler(w http.ResponseWriter, r *http.Request) {
+	// Browsers cannot set headers on WebSocket requests, so also accept ?token=
+	if !sm.authorized(r) && !sm.validToken(r.URL.Query().Get("token")) {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		return
+	}
+
+	// Subscribing before the upgrade lets a client over the limit get a plain error
+	replay, lines, err := sm.logStream.subscribe()
+	if err != nil {
+		sm.logger.Warnf("Rejected log stream client %s: %v", r.RemoteAddr, err)
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": err.Error()})
+		return
+	}
+	defer sm.logStream.unsubscribe(lines)
+
+	conn, err := logUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		sm.logger.Errorf("Log stream upgrade failed: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	sm.logger.Infof("Log stream client connected: %s", r.RemoteAddr)
+	defer sm.logger.Infof("Log stream client disconnected: %s", r.RemoteAddr)
+
+	// Read until the client goes away so disconnects are noticed promptly
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for _, line := range replay {
+		conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(line)); err != nil {
+			return
+		}
+	}
+
+	for {
+		select {
+		case line := <-lines:
+			conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(line)); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		case <-sm.ctx.Done():
+			conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseGoingAway, "service manager shutting down"),
+				time.Now().Add(time.Second))
+			return
+		}
+	}
+}
+
+// lineRing keeps the most recent lines written to it
+type lineRing struct {
+	lines []string
+	next  int
+	full  bool
+}
+
+func newLineRing(size int) *lineRing {
+	return &lineRing{lines: make([]string, size)}
+}
+
+// add records a line, overwriting the oldest once the ring is full
+func (lr *lineRing) add(line string) {
+	lr.lines[lr.next] = line
+	lr.next = (lr.next + 1) % len(lr.lines)
+	if lr.next == 0 {
+		lr.full = true
+	}
+}
+
+// truncateLine shortens line to at most max bytes without splitting a UTF-8 sequence
+func truncateLine(line string, max int) string {
+	if len(line) <= max {
+		return line
+	}
+	line = line[:max]
+	for len(line) > 0 && !utf8.ValidString(line) {
+		line = line[:len(line)-1]
+	}
+	return line + "…"
+}
+
+// snapshot returns the recorded lines, oldest first
+func (lr *lineRing) snapshot() []string {
+	if !lr.full {
+		return append([]string(nil), lr.lines[:lr.next]...)
+	}
+	return append(append([]string(nil), lr.lines[lr.next:]...), lr.lines[:lr.next]...)
+}
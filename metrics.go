@@ -0,0 +1,136 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// metrics holds the Prometheus collectors exported on /metrics
+type metrics struct {
+	registry *prometheus.Registry
+
+	pythonRestarts      prometheus.Counter
+	dbReconnectAttempts prometheus.Counter
+	dbPingFailures      prometheus.Counter
+	dbHealthy           prometheus.Gauge
+	dbReplicaHealthy    prometheus.Gauge
+	pythonAlive         prometheus.Gauge
+
+	dbPingDuration       prometheus.Histogram
+	pythonHealthDuration prometheus.Histogram
+
+	configReloads      prometheus.Counter
+	configReloadErrors prometheus.Counter
+	configLastReload   prometheus.Gauge
+}
+
+func newMetrics() *metrics {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+		pythonRestarts: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "service_manager",
+			Name:      "python_restarts_total",
+			Help:      "Number of times the Python server has been restarted after a crash.",
+		}),
+		dbReconnectAttempts: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "service_manager",
+			Name:      "db_reconnect_attempts_total",
+			Help:      "Number of database reconnection attempts.",
+		}),
+		dbPingFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "service_manager",
+			Name:      "db_ping_failures_total",
+			Help:      "Number of failed database health checks.",
+		}),
+		dbHealthy: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "service_manager",
+			Name:      "db_healthy",
+			Help:      "Whether the last database health check succeeded (1) or failed (0).",
+		}),
+		dbReplicaHealthy: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "service_manager",
+			Name:      "db_replica_healthy",
+			Help:      "Whether the last read replica health check succeeded (1) or failed (0).",
+		}),
+		pythonAlive: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "service_manager",
+			Name:      "python_alive",
+			Help:      "Number of Python server processes running.",
+		}),
+		dbPingDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: "service_manager",
+			Name:      "db_ping_duration_seconds",
+			Help:      "Duration of database health checks.",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 2, 12),
+		}),
+		pythonHealthDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: "service_manager",
+			Name:      "python_health_duration_seconds",
+			Help:      "Duration of answered Python health endpoint polls.",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 2, 12),
+		}),
+		configReloads: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "service_manager",
+			Name:      "config_reloads_total",
+			Help:      "Number of successful config reloads.",
+		}),
+		configReloadErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "service_manager",
+			Name:      "config_reload_errors_total",
+			Help:      "Number of config reloads rejected because the config failed to load or validate.",
+		}),
+		configLastReload: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "service_manager",
+			Name:      "config_last_reload_timestamp",
+			Help:      "Unix time of the last successful config reload.",
+		}),
+	}
+
+	// build_info is always 1; its labels identify the running build
+	build := currentBuild()
+	buildInfo := prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "service_manager",
+		Name:      "build_info",
+		Help:      "Build information of the service manager; always 1.",
+		ConstLabels: prometheus.Labels{
+			"version":    build.Version,
+			"commit":     build.Commit,
+			"go_version": build.GoVersion,
+		},
+	})
+	buildInfo.Set(1)
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		buildInfo,
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.pythonRestarts,
+		m.dbReconnectAttempts,
+		m.dbPingFailures,
+		m.dbHealthy,
+		m.dbReplicaHealthy,
+		m.pythonAlive,
+		m.dbPingDuration,
+		m.pythonHealthDuration,
+		m.configReloads,
+		m.configReloadErrors,
+		m.configLastReload,
+	)
+	return m
+}
+
+// handler serves the registered metrics in the Prometheus exposition format
+func (m *metrics) handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// boolToFloat converts a health flag to a gauge value
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
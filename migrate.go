@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// runMigrations applies the .sql files in dir that have not been applied yet, in
+// lexical order, each in its own transaction. Applied versions (file names
+// without .sql) are tracked in the schema_migrations table.
+func (sm *ServiceManager) runMigrations(ctx context.Context, dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read migrations directory: %w", err)
+	}
+
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".sql") {
+			files = append(files, entry.Name())
+		}
+	}
+	if len(files) == 0 {
+		sm.logger.Infof("No migrations found in %s", dir)
+		return nil
+	}
+	slices.Sort(files)
+
+	db := sm.database()
+	if _, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version VARCHAR(255) PRIMARY KEY,
+		applied_at TIMESTAMP NOT NULL
+	)`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	applied, err := sm.appliedMigrations(ctx)
+	if err != nil {
+		return err
+	}
+
+	insert := fmt.Sprintf("INSERT INTO schema_migrations (version, applied_at) VALUES (%s)",
+		placeholders(sm.cfg().Database.Driver, 2))
+	count := 0
+	for _, file := range files {
+		version := strings.TrimSuffix(file, ".sql")
+		if applied[version] {
+			continue
+		}
+
+		script, err := os.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			return fmt.Errorf("failed to read migration %s: %w", file, err)
+		}
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("failed to begin migration %s: %w", file, err)
+		}
+		if _, err := tx.ExecContext(ctx, string(script)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %s failed: %w", file, err)
+		}
+		if _, err := tx.ExecContext(ctx, insert, version, time.Now().UTC()); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to record migration %s: %w", file, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration %s: %w", file, err)
+		}
+
+		sm.logger.Infof("Applied migration %s", file)
+		count++
+	}
+
+	sm.logger.Infof("Database schema is up to date (%d migrations applied, %d already present)", count, len(applied))
+	return nil
+}
+
+// appliedMigrations returns the versions recorded in schema_migrations
+func (sm *ServiceManager) appliedMigrations(ctx context.Context) (map[string]bool, error) {
+	rows, err := sm.database().QueryContext(ctx, "SELECT version FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[string]bool)
+	for rows.Next() {
+		var version string
+		if err := rows.Scan(&version); err != nil {
+			return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+		}
+		applied[version] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	return applied, nil
+}
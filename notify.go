@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// webhookTimeout bounds a single webhook delivery attempt
+	webhookTimeout = 3 * time.Second
+	// webhookAttempts is how many times a webhook delivery is tried
+	webhookAttempts = 3
+	// webhookRetryDelay is the pause between webhook delivery attempts
+	webhookRetryDelay = time.Second
+	// stoppedNotifyTimeout bounds the whole "stopped" delivery, retries
+	// included, so a dead webhook cannot hold up shutdown
+	stoppedNotifyTimeout = 2 * time.Second
+)
+
+// lifecycleEvent is the payload POSTed to the notification webhook
+type lifecycleEvent struct {
+	Event     string    `json:"event"`
+	Hostname  string    `json:"hostname"`
+	Timestamp time.Time `json:"timestamp"`
+	Version   string    `json:"version"`
+}
+
+// slackMessage is the payload of a Slack incoming webhook
+type slackMessage struct {
+	Text string `json:"text"`
+}
+
+// alertLimiter allows at most one alert per event type per cooldown and counts
+// the ones it holds back
+type alertLimiter struct {
+	mu         sync.Mutex
+	last       map[string]time.Time
+	suppressed map[string]int
+}
+
+// allow reports whether an alert for event may be sent now. When it may, it
+// also returns how many alerts for event were suppressed since the last one. A
+// negative cooldown allows every alert.
+func (l *alertLimiter) allow(event string, cooldown time.Duration) (bool, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.last == nil {
+		l.last = make(map[string]time.Time)
+		l.suppressed = make(map[string]int)
+	}
+	if last, ok := l.last[event]; ok && cooldown > 0 && time.Since(last) < cooldown {
+		l.suppressed[event]++
+		return false, 0
+	}
+	suppressed := l.suppressed[event]
+	l.last[event] = time.Now()
+	l.suppressed[event] = 0
+	return true, suppressed
+}
+
+// notify POSTs a lifecycle event to the configured webhook, if any, giving up
+// when ctx is done. Failures are logged and otherwise ignored.
+func (sm *ServiceManager) notify(ctx context.Context, event string) {
+	webhook := sm.cfg().Notifications.WebhookURL
+	if webhook == "" {
+		return
+	}
+
+	hostname, _ := os.Hostname()
+	sm.sendWebhook(ctx, event+" notification", webhook, lifecycleEvent{
+		Event:     event,
+		Hostname:  hostname,
+		Timestamp: time.Now().UTC(),
+		Version:   version,
+	})
+}
+
+// alert posts a message to Slack, if configured, without blocking. Alerts for the
+// same event are limited to one per cooldown; the next one sent says how many
+// were suppressed in between.
+func (sm *ServiceManager) alert(event, format string, args ...any) {
+	cfg := sm.cfg().Notifications
+	if cfg.SlackWebhook == "" {
+		return
+	}
+
+	allowed, suppressed := sm.alerts.allow(event, cfg.Cooldown)
+	if !allowed {
+		sm.logger.Debugf("Suppressed %s alert during cooldown", event)
+		return
+	}
+
+	hostname, _ := os.Hostname()
+	text := fmt.Sprintf("[%s] %s", hostname, fmt.Sprintf(format, args...))
+	if suppressed > 0 {
+		text += fmt.Sprintf(" (%d similar alerts suppressed since the last one)", suppressed)
+	}
+	go sm.sendWebhook(context.Background(), event+" alert", cfg.SlackWebhook, slackMessage{Text: text})
+}
+
+// sendWebhook POSTs payload as JSON to url, retrying failed deliveries a few
+// times until ctx is done. Failures are logged and otherwise ignored.
+func (sm *ServiceManager) sendWebhook(ctx context.Context, what, url string, payload any) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		sm.logger.Errorf("Failed to encode %s: %v", what, err)
+		return
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := postWebhook(ctx, url, body)
+		if err == nil {
+			sm.logger.Debugf("Sent %s", what)
+			return
+		}
+		if attempt == webhookAttempts || ctx.Err() != nil {
+			sm.logger.Warnf("Failed to send %s after %d attempts: %v", what, attempt, err)
+			return
+		}
+		sm.logger.Debugf("Failed to send %s (attempt %d/%d), retrying: %v", what, attempt, webhookAttempts, err)
+
+		timer := time.NewTimer(webhookRetryDelay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			sm.logger.Warnf("Failed to send %s after %d attempts: %v", what, attempt, ctx.Err())
+			return
+		}
+	}
+}
+
+// postWebhook delivers a JSON body to url, failing on network errors and
+// non-2xx responses
+func postWebhook(ctx context.Context, url string, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
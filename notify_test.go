@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNotifyRetriesWebhook(t *testing.T) {
+	// The first delivery fails, so the event arrives twice
+	var mu sync.Mutex
+	var events []lifecycleEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event lifecycleEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("failed to decode notification: %v", err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+		if len(events) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	sm := newTestManager(t, "notifications:\n  webhook_url: "+server.URL+"\n")
+	sm.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(events) != 2 {
+		t.Fatalf("webhook received %d deliveries, want a failed one and a retry", len(events))
+	}
+	for _, event := range events {
+		if event.Event != "stopped" || event.Hostname == "" || event.Version != version {
+			t.Errorf("notification = %+v, want a stopped event with hostname and version", event)
+		}
+	}
+}
+
+func TestStoppedNotificationDoesNotBlockShutdown(t *testing.T) {
+	// An endpoint that accepts the request and never answers
+	hung := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-hung
+	}))
+	defer server.Close()
+	defer close(hung)
+
+	sm := newTestManager(t, "notifications:\n  webhook_url: "+server.URL+"\n")
+
+	start := time.Now()
+	sm.Wait()
+	if elapsed := time.Since(start); elapsed > stoppedNotifyTimeout+time.Second {
+		t.Errorf("Wait took %s with a hung webhook, want at most about %s", elapsed, stoppedNotifyTimeout)
+	}
+}
+
+func TestAlertCooldown(t *testing.T) {
+	var limiter alertLimiter
+	if ok, _ := limiter.allow("python_crash", 50*time.Millisecond); !ok {
+		t.Fatal("first alert was suppressed")
+	}
+	if ok, _ := limiter.allow("python_crash", 50*time.Millisecond); ok {
+		t.Error("second alert within the cooldown was sent")
+	}
+	if ok, _ := limiter.allow("database_down", 50*time.Millisecond); !ok {
+		t.Error("alert of another kind was suppressed")
+	}
+	time.Sleep(60 * time.Millisecond)
+	ok, suppressed := limiter.allow("python_crash", 50*time.Millisecond)
+	if !ok || suppressed != 1 {
+		t.Errorf("alert after the cooldown = %t with %d suppressed, want true with 1", ok, suppressed)
+	}
+}
+
+func TestAlertCooldownDisabled(t *testing.T) {
+	sm := newTestManager(t, "notifications:\n  cooldown: -1s\n")
+	cooldown := sm.cfg().Notifications.Cooldown
+
+	for i := range 3 {
+		if ok, _ := sm.alerts.allow("python_crash", cooldown); !ok {
+			t.Errorf("alert %d was suppressed with the cooldown off", i+1)
+		}
+	}
+}
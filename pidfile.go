@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// writePidFile writes pid to path, replacing any stale file. A warning is logged
+// if the PID in the old file still belongs to a running process.
+func (sm *ServiceManager) writePidFile(path string, pid int) error {
+	if data, err := os.ReadFile(path); err == nil {
+		if old, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil && old != pid && processAlive(old) {
+			sm.logger.Warnf("PID file %s refers to running process %d, overwriting", path, old)
+		}
+	}
+
+	if err := os.WriteFile(path, []byte(strconv.Itoa(pid)+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write PID file: %w", err)
+	}
+	return nil
+}
+
+// removePidFile removes a PID file written by writePidFile
+func (sm *ServiceManager) removePidFile(path string) {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		sm.logger.Warnf("Failed to remove PID file %s: %v", path, err)
+	}
+}
+
+// processAlive reports whether a process with the given PID exists
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
@@ -0,0 +1,101 @@
+//go:build linux
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// startReaper registers the manager as a child subreaper and starts collecting
+// exited orphans whenever SIGCHLD arrives
+func (sm *ServiceManager) startReaper() {
+	if err := unix.Prctl(unix.PR_SET_CHILD_SUBREAPER, 1, 0, 0, 0); err != nil {
+		sm.logger.Warnf("Failed to become a child subreaper, only direct children will be reaped: %v", err)
+	}
+
+	sigchld := make(chan os.Signal, 1)
+	signal.Notify(sigchld, syscall.SIGCHLD)
+
+	sm.wg.Add(1)
+	go func() {
+		defer sm.wg.Done()
+		defer signal.Stop(sigchld)
+		sm.logger.Info("Reaping orphaned child processes")
+
+		for {
+			select {
+			case <-sigchld:
+				sm.reapOrphans()
+			case <-sm.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// reapOrphans collects every zombie child except the worker processes, whose exit
+// status belongs to their own cmd.Wait
+func (sm *ServiceManager) reapOrphans() {
+	sm.reapMu.Lock()
+	defer sm.reapMu.Unlock()
+
+	workers := make(map[int]bool, len(sm.workers))
+	for _, w := range sm.workers {
+		if pid := w.pid(); pid != 0 {
+			workers[pid] = true
+		}
+	}
+
+	for _, pid := range zombieChildren() {
+		if workers[pid] {
+			continue
+		}
+		var status unix.WaitStatus
+		if reaped, err := unix.Wait4(pid, &status, unix.WNOHANG, nil); err == nil && reaped == pid {
+			sm.logger.Debugf("Reaped orphaned process %d (exit status %d)", pid, status.ExitStatus())
+		}
+	}
+}
+
+// zombieChildren lists the PIDs of this process's children that have exited but
+// not been waited for
+func zombieChildren() []int {
+	paths, err := filepath.Glob("/proc/[0-9]*/stat")
+	if err != nil {
+		return nil
+	}
+
+	self := os.Getpid()
+	var pids []int
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue // the process is already gone
+		}
+		// The command name is in parentheses and may itself contain spaces or
+		// parentheses, so parse the fields after the last one: state, then ppid
+		stat := string(data)
+		end := strings.LastIndexByte(stat, ')')
+		if end < 0 {
+			continue
+		}
+		fields := strings.Fields(stat[end+1:])
+		if len(fields) < 2 || fields[0] != "Z" {
+			continue
+		}
+		if ppid, err := strconv.Atoi(fields[1]); err != nil || ppid != self {
+			continue
+		}
+		if pid, err := strconv.Atoi(strings.Fields(stat)[0]); err == nil {
+			pids = append(pids, pid)
+		}
+	}
+	return pids
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package main
+
+// startReaper is a no-op on platforms without child subreapers
+func (sm *ServiceManager) startReaper() {
+	sm.logger.Warn("reap is not supported on this platform, ignoring")
+}
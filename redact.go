@@ -0,0 +1,32 @@
+package main
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// redacted replaces secrets in log output and API responses
+const redacted = "****"
+
+// passwordParam matches a password in key=value DSNs and URL query strings
+var passwordParam = regexp.MustCompile(`(password=)[^\s&]*`)
+
+// redactDSN returns dsn with the password replaced, for logging. It handles
+// connection URLs, key=value DSNs and, via the configured password, any other
+// format such as MySQL's user:pass@tcp(host)/db.
+func redactDSN(dsn, password string) string {
+	if u, err := url.Parse(dsn); err == nil && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			// url escapes the asterisks, so undo that for readability
+			u.User = url.UserPassword(u.User.Username(), redacted)
+			dsn = strings.Replace(u.String(), url.PathEscape(redacted), redacted, 1)
+		}
+	}
+
+	dsn = passwordParam.ReplaceAllString(dsn, "${1}"+redacted)
+	if password != "" {
+		dsn = strings.ReplaceAll(dsn, password, redacted)
+	}
+	return dsn
+}
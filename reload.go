@@ -0,0 +1,311 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+// configChange is a single field that differs between two configs
+type configChange struct {
+	Field string `json:"field"`
+	Old   any    `json:"old"`
+	New   any    `json:"new"`
+}
+
+// secretFields are redacted from config diffs
+var secretFields = map[string]bool{
+	"database.password":           true,
+	"database.url":                true,
+	"database.replica_url":        true,
+	"server.admin_token":          true,
+	"server.env":                  true,
+	"notifications.webhook_url":   true,
+	"notifications.slack_webhook": true,
+}
+
+// hotReloadFields can be applied without restarting anything. Any other
+// changed field is reported and ignored until the manager restarts.
+var hotReloadFields = []string{
+	"logging.level",
+	"database.check_interval",
+	"database.max_retries",
+	"database.health_query",
+	"database.initial_backoff",
+	"database.max_backoff",
+	"database.failure_threshold",
+	"server.shutdown_timeout",
+	"server.health_shutdown_timeout",
+	"server.ready_timeout",
+	"server.restart_policy",
+	"server.restart_on_success",
+	"server.exit_code_actions",
+	"server.default_exit_action",
+	"server.reload_restarts_python",
+	"server.python_health_host",
+	"server.python_health_path",
+	"server.python_health_timeout",
+	"server.drain_delay",
+	"server.drain_path",
+	"server.protected_paths",
+	"server.degraded_latency",
+}
+
+// pythonCommandFields change how Python is launched. They are applied with a
+// rolling restart when reload_restarts_python is set.
+var pythonCommandFields = []string{
+	"server.python_path",
+	"server.python_candidates",
+	"server.script_path",
+	"server.exec_mode",
+	"server.env",
+	"server.script_args",
+	"server.working_dir",
+}
+
+// cfg returns the current configuration
+func (sm *ServiceManager) cfg() *Config {
+	sm.configMu.RLock()
+	defer sm.configMu.RUnlock()
+	return sm.config
+}
+
+// reloadConfig reloads the config file and applies the fields that can be changed
+// at runtime. A config that fails to load or validate is rejected as a whole. It
+// reports whether a changed Python command started a rolling restart.
+func (sm *ServiceManager) reloadConfig() (restarting bool, err error) {
+	loaded, err := loadConfig(sm.configPath)
+	if err != nil {
+		sm.metrics.configReloadErrors.Inc()
+		return false, err
+	}
+
+	sm.configMu.Lock()
+	previous := sm.config
+
+	// The running python_path was resolved from the candidates at startup, so it
+	// only counts as changed if the candidates did
+	if len(loaded.Server.PythonCandidates) > 0 && slices.Equal(loaded.Server.PythonCandidates, previous.Server.PythonCandidates) {
+		loaded.Server.PythonPath = previous.Server.PythonPath
+	}
+
+	next := *previous
+	var applied, ignored, command []configChange
+	for _, change := range diffConfig(previous, loaded) {
+		switch {
+		case isHotReloadField(change.Field):
+			applied = append(applied, change)
+		case loaded.Server.ReloadRestartsPython && slices.Contains(pythonCommandFields, change.Field):
+			command = append(command, change)
+		default:
+			ignored = append(ignored, change)
+		}
+	}
+
+	next.Logging.Level = loaded.Logging.Level
+	next.Database.CheckInterval = loaded.Database.CheckInterval
+	next.Database.MaxRetries = loaded.Database.MaxRetries
+	next.Database.HealthQuery = loaded.Database.HealthQuery
+	next.Database.InitialBackoff = loaded.Database.InitialBackoff
+	next.Database.MaxBackoff = loaded.Database.MaxBackoff
+	next.Database.FailureThreshold = loaded.Database.FailureThreshold
+	next.Server.ShutdownTimeout = loaded.Server.ShutdownTimeout
+	next.Server.HealthShutdownTimeout = loaded.Server.HealthShutdownTimeout
+	next.Server.ReadyTimeout = loaded.Server.ReadyTimeout
+	next.Server.RestartPolicy = loaded.Server.RestartPolicy
+	next.Server.RestartOnSuccess = loaded.Server.RestartOnSuccess
+	next.Server.ExitCodeActions = loaded.Server.ExitCodeActions
+	next.Server.DefaultExitAction = loaded.Server.DefaultExitAction
+	next.Server.ReloadRestartsPython = loaded.Server.ReloadRestartsPython
+	next.Server.PythonHealthHost = loaded.Server.PythonHealthHost
+	next.Server.PythonHealthPath = loaded.Server.PythonHealthPath
+	next.Server.PythonHealthTimeout = loaded.Server.PythonHealthTimeout
+	next.Server.DrainDelay = loaded.Server.DrainDelay
+	next.Server.DrainPath = loaded.Server.DrainPath
+	next.Server.ProtectedPaths = loaded.Server.ProtectedPaths
+	next.Server.DegradedLatency = loaded.Server.DegradedLatency
+
+	if len(command) > 0 {
+		if loaded.Server.ExecMode == "interpreter" {
+			if err := sm.resolvePythonInterpreter(loaded); err != nil {
+				sm.configMu.Unlock()
+				sm.metrics.configReloadErrors.Inc()
+				return false, err
+			}
+		}
+		next.Server.PythonPath = loaded.Server.PythonPath
+		next.Server.PythonCandidates = loaded.Server.PythonCandidates
+		next.Server.ScriptPath = loaded.Server.ScriptPath
+		next.Server.ExecMode = loaded.Server.ExecMode
+		next.Server.Env = loaded.Server.Env
+		next.Server.ScriptArgs = loaded.Server.ScriptArgs
+		next.Server.WorkingDir = loaded.Server.WorkingDir
+	}
+
+	sm.config = &next
+	sm.previousConfig = previous
+	sm.configMu.Unlock()
+
+	level, _ := parseLogLevel(next.Logging.Level) // validated in loadConfig
+	sm.logger.SetLevel(level)
+
+	sm.metrics.configReloads.Inc()
+	sm.metrics.configLastReload.SetToCurrentTime()
+
+	for _, change := range applied {
+		sm.logger.Infof("Config reload applied %s: %v -> %v", change.Field, change.Old, change.New)
+	}
+	for _, change := range ignored {
+		sm.logger.Warnf("Config reload ignored %s (requires restart): %v -> %v", change.Field, change.Old, change.New)
+	}
+	if len(applied) == 0 && len(ignored) == 0 && len(command) == 0 {
+		sm.logger.Info("Config reloaded, no changes")
+	}
+
+	// Pick up a new Python command with a rolling restart
+	if len(command) > 0 {
+		for _, change := range command {
+			sm.logger.Infof("Config reload changed %s: %v -> %v, restarting Python", change.Field, change.Old, change.New)
+		}
+		go func() {
+			if err := sm.restartWorkers(sm.ctx); err != nil {
+				sm.logger.Errorf("Python restart after config reload failed: %v", err)
+			}
+		}()
+	}
+	return len(command) > 0, nil
+}
+
+// isHotReloadField reports whether a changed field can be applied at runtime
+func isHotReloadField(field string) bool {
+	for _, hot := range hotReloadFields {
+		if field == hot || strings.HasPrefix(field, hot+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// configDiffHandler returns the differences between the previous and current config
+func (sm *ServiceManager) configDiffHandler(w http.ResponseWriter, r *http.Request) {
+	if !sm.requireAdmin(w, r, http.MethodGet) {
+		return
+	}
+
+	sm.configMu.RLock()
+	previous, current := sm.previousConfig, sm.config
+	sm.configMu.RUnlock()
+
+	if previous == nil {
+		writeJSON(w, http.StatusOK, map[string]any{"reloaded": false, "changes": []configChange{}})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"reloaded": true, "changes": diffConfig(previous, current)})
+}
+
+// diffConfig returns the fields that differ between two configs, named by their
+// YAML paths, with secret values redacted
+func diffConfig(previous, current *Config) []configChange {
+	changes := []configChange{}
+	diffValues("", reflect.ValueOf(*previous), reflect.ValueOf(*current), &changes)
+	return changes
+}
+
+func diffValues(path string, a, b reflect.Value, changes *[]configChange) {
+	if a.Kind() == reflect.Struct {
+		for i := 0; i < a.NumField(); i++ {
+			name, _, _ := strings.Cut(a.Type().Field(i).Tag.Get("yaml"), ",")
+			if name == "" || name == "-" {
+				name = strings.ToLower(a.Type().Field(i).Name)
+			}
+			if path != "" {
+				name = path + "." + name
+			}
+			diffValues(name, a.Field(i), b.Field(i), changes)
+		}
+		return
+	}
+
+	if reflect.DeepEqual(a.Interface(), b.Interface()) {
+		return
+	}
+
+	change := configChange{Field: path, Old: displayValue(a), New: displayValue(b)}
+	if secretFields[path] {
+		change.Old, change.New = redacted, redacted
+	}
+	*changes = append(*changes, change)
+}
+
+// configHandler returns the configuration in effect, after defaults and the
+// password file are applied, with secrets redacted
+func (sm *ServiceManager) configHandler(w http.ResponseWriter, r *http.Request) {
+	if !sm.requireAdmin(w, r, http.MethodGet) {
+		return
+	}
+
+	cfg := sm.cfg()
+	writeJSON(w, http.StatusOK, configValues("", reflect.ValueOf(*cfg), cfg.Database.Password))
+}
+
+// configValues renders a config value keyed by YAML names, the same way config
+// diffs name fields. Secret fields are redacted when set; connection URLs keep
+// everything but the password.
+func configValues(path string, v reflect.Value, password string) any {
+	if v.Kind() == reflect.Struct {
+		fields := make(map[string]any, v.NumField())
+		for i := 0; i < v.NumField(); i++ {
+			name, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("yaml"), ",")
+			if name == "" || name == "-" {
+				name = strings.ToLower(v.Type().Field(i).Name)
+			}
+			field := name
+			if path != "" {
+				field = path + "." + name
+			}
+			fields[name] = configValues(field, v.Field(i), password)
+		}
+		return fields
+	}
+
+	switch {
+	case !secretFields[path] || v.IsZero():
+		return displayValue(v)
+	case path == "database.url" || path == "database.replica_url":
+		return redactDSN(v.String(), password)
+	case v.Kind() == reflect.Map:
+		// Keep the names visible so it is clear what is set
+		values := make(map[string]string, v.Len())
+		for _, key := range v.MapKeys() {
+			values[key.String()] = redacted
+		}
+		return values
+	default:
+		return redacted
+	}
+}
+
+// displayValue renders durations as strings so diffs read like the config file
+func displayValue(v reflect.Value) any {
+	if d, ok := v.Interface().(time.Duration); ok {
+		return d.String()
+	}
+	return v.Interface()
+}
+
+// waitForReload reloads the config each time a reload signal arrives
+func (sm *ServiceManager) waitForReload() {
+	for {
+		select {
+		case <-sm.reload:
+			sm.logger.Infof("Reload signal received, reloading %s", sm.configPath)
+			if _, err := sm.reloadConfig(); err != nil {
+				sm.logger.Errorf("Config reload failed, keeping current config: %v", err)
+			}
+		case <-sm.ctx.Done():
+			return
+		}
+	}
+}
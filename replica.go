@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// replicaReport is the read replica's part of the health report
+type replicaReport struct {
+	Healthy       bool    `json:"healthy"`
+	Required      bool    `json:"required"`
+	LatencyMillis float64 `json:"latency_ms"`
+}
+
+// initReplica opens the read replica. Failing to reach it is only logged, as
+// database/sql connects again by itself and the monitor keeps checking it.
+func (sm *ServiceManager) initReplica(ctx context.Context) error {
+	cfg := sm.cfg()
+	db, err := sql.Open(cfg.Database.Driver, cfg.Database.ReplicaURL)
+	if err != nil {
+		return fmt.Errorf("failed to open replica database: %w", err)
+	}
+	db.SetMaxOpenConns(cfg.Database.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.Database.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.Database.ConnMaxLifetime)
+
+	sm.dbMu.Lock()
+	previous := sm.replica
+	sm.replica = db
+	sm.dbMu.Unlock()
+	if previous != nil {
+		previous.Close()
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if _, err := sm.pingReplica(ctx); err != nil {
+		sm.setReplicaHealthy(false)
+		sm.logger.Warnf("Replica database not reachable, will keep checking: %v", err)
+		return nil
+	}
+	sm.setReplicaHealthy(true)
+	sm.logger.Infof("Replica database connection established: %s", redactDSN(cfg.Database.ReplicaURL, ""))
+	return nil
+}
+
+// replicaDatabase returns the read replica handle, or nil when none is configured
+func (sm *ServiceManager) replicaDatabase() *sql.DB {
+	sm.dbMu.RLock()
+	defer sm.dbMu.RUnlock()
+	return sm.replica
+}
+
+// closeReplica closes the read replica handle, if any
+func (sm *ServiceManager) closeReplica() {
+	sm.dbMu.Lock()
+	db := sm.replica
+	sm.replica = nil
+	sm.dbMu.Unlock()
+
+	if db != nil {
+		db.Close()
+		sm.logger.Info("Replica database connection closed")
+	}
+}
+
+// pingReplica runs the health query against the read replica
+func (sm *ServiceManager) pingReplica(ctx context.Context) (time.Duration, error) {
+	db := sm.replicaDatabase()
+	if db == nil {
+		return 0, fmt.Errorf("no replica connection")
+	}
+
+	start := time.Now()
+	err := sm.runHealthQuery(ctx, db)
+	return time.Since(start), err
+}
+
+// checkReplicaHealth checks the read replica, logging when it goes down or
+// comes back
+func (sm *ServiceManager) checkReplicaHealth() {
+	ctx, cancel := context.WithTimeout(sm.ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := sm.pingReplica(ctx)
+	if sm.ctx.Err() != nil {
+		return
+	}
+	wasHealthy := sm.setReplicaHealthy(err == nil)
+	switch {
+	case err != nil && wasHealthy:
+		sm.logger.Warnf("Replica database health check failed: %v", err)
+	case err != nil:
+		sm.logger.Debugf("Replica database still unavailable: %v", err)
+	case !wasHealthy:
+		sm.logger.Info("Replica database is healthy again")
+	}
+}
+
+// setReplicaHealthy records the replica's health and returns the previous value
+func (sm *ServiceManager) setReplicaHealthy(healthy bool) bool {
+	sm.metrics.dbReplicaHealthy.Set(boolToFloat(healthy))
+	sm.recordStatus("replica", healthy)
+	return sm.replicaHealthy.Swap(healthy)
+}
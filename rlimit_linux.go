@@ -0,0 +1,79 @@
+//go:build linux
+
+package main
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// cpuKillGrace is how many CPU seconds past the soft limit (SIGXCPU) the process
+// gets before the hard limit kills it
+const cpuKillGrace = 5
+
+// applyResourceLimits applies the configured resource limits to the Python process
+func (sm *ServiceManager) applyResourceLimits(pid int) {
+	cfg := sm.cfg().Server
+	if want := cfg.RlimitNofile; want > 0 {
+		sm.applyNofileLimit(pid, want)
+	}
+	if cfg.MemoryLimitMB > 0 {
+		bytes := cfg.MemoryLimitMB << 20
+		sm.lowerLimit(pid, unix.RLIMIT_AS, "memory limit", bytes, bytes, fmt.Sprintf("%d MB", cfg.MemoryLimitMB))
+	}
+	if cfg.CPULimitSeconds > 0 {
+		sm.lowerLimit(pid, unix.RLIMIT_CPU, "CPU time limit", cfg.CPULimitSeconds, cfg.CPULimitSeconds+cpuKillGrace,
+			fmt.Sprintf("%ds", cfg.CPULimitSeconds))
+	}
+}
+
+// lowerLimit sets a resource limit of the process to soft and hard, which never
+// needs privileges as long as they do not exceed the current hard limit. Values
+// above it are capped.
+func (sm *ServiceManager) lowerLimit(pid, resource int, name string, soft, hard uint64, display string) {
+	var current unix.Rlimit
+	if err := unix.Prlimit(pid, resource, nil, &current); err != nil {
+		sm.logger.Warnf("Failed to read %s for PID %d: %v", name, pid, err)
+		return
+	}
+
+	limit := unix.Rlimit{Cur: min(soft, current.Max), Max: min(hard, current.Max)}
+	if limit.Cur < soft {
+		sm.logger.Warnf("Python %s %s exceeds the hard limit %d, capping it", name, display, current.Max)
+	}
+	if err := unix.Prlimit(pid, resource, &limit, nil); err != nil {
+		sm.logger.Warnf("Failed to set %s for PID %d: %v", name, pid, err)
+		return
+	}
+	sm.logger.Infof("Set Python %s to %s", name, display)
+}
+
+// applyNofileLimit raises the process's open file limit to want. The hard limit
+// is raised too when needed; if that is not permitted the soft limit is capped
+// at the current hard limit.
+func (sm *ServiceManager) applyNofileLimit(pid int, want uint64) {
+	var current unix.Rlimit
+	if err := unix.Prlimit(pid, unix.RLIMIT_NOFILE, nil, &current); err != nil {
+		sm.logger.Errorf("Failed to read file descriptor limit for PID %d: %v", pid, err)
+		return
+	}
+
+	limit := unix.Rlimit{Cur: want, Max: current.Max}
+	if want > current.Max {
+		limit.Max = want
+		if err := unix.Prlimit(pid, unix.RLIMIT_NOFILE, &limit, nil); err == nil {
+			sm.logger.Infof("Set Python file descriptor limit to %d (hard limit raised)", want)
+			return
+		}
+		sm.logger.Warnf("rlimit_nofile %d exceeds hard limit %d and cannot be raised, using %d",
+			want, current.Max, current.Max)
+		limit = unix.Rlimit{Cur: current.Max, Max: current.Max}
+	}
+
+	if err := unix.Prlimit(pid, unix.RLIMIT_NOFILE, &limit, nil); err != nil {
+		sm.logger.Errorf("Failed to set file descriptor limit for PID %d: %v", pid, err)
+		return
+	}
+	sm.logger.Infof("Set Python file descriptor limit to %d", limit.Cur)
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package main
+
+// applyResourceLimits is a no-op on platforms without prlimit
+func (sm *ServiceManager) applyResourceLimits(pid int) {
+	if sm.cfg().Server.RlimitNofile > 0 {
+		sm.logger.Warn("rlimit_nofile is not supported on this platform, ignoring")
+	}
+	if sm.cfg().Server.MemoryLimitMB > 0 || sm.cfg().Server.CPULimitSeconds > 0 {
+		sm.logger.Warn("memory_limit_mb and cpu_limit_seconds are not supported on this platform, ignoring")
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// rotatingFile is an io.Writer that appends to a file and rotates it once it
+// exceeds maxSize bytes, keeping up to maxBackups old files as path.1, path.2, ...
+// Backups older than maxAge, when positive, are removed on rotation. It is safe
+// for concurrent use.
+type rotatingFile struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	maxAge     time.Duration
+	file       *os.File
+	size       int64
+	closed     bool
+	// failing is set while rotations fail, so the failure is reported once
+	failing bool
+}
+
+// openRotatingFile opens path for appending with the given rotation policy
+func openRotatingFile(path string, maxSizeMB, maxBackups int, maxAge time.Duration) (*rotatingFile, error) {
+	rf := &rotatingFile{
+		path:       path,
+		maxSize:    int64(maxSizeMB) * 1024 * 1024,
+		maxBackups: maxBackups,
+		maxAge:     maxAge,
+	}
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+	return rf, nil
+}
+
+func (rf *rotatingFile) Write(p []byte) (int, error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.closed {
+		return 0, fmt.Errorf("log file %s is closed", rf.path)
+	}
+
+	if rf.file != nil && rf.size > 0 && rf.size+int64(len(p)) > rf.maxSize {
+		rf.rotate()
+	}
+	// The file could not be reopened after rotating; keep trying, and write to
+	// stderr meanwhile so no output is lost
+	if rf.file == nil {
+		if err := rf.open(); err != nil {
+			return os.Stderr.Write(p)
+		}
+	}
+
+	n, err := rf.file.Write(p)
+	rf.size += int64(n)
+	return n, err
+}
+
+// Close closes the current log file
+func (rf *rotatingFile) Close() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	rf.closed = true
+	if rf.file == nil {
+		return nil
+	}
+	err := rf.file.Close()
+	rf.file = nil
+	return err
+}
+
+// open opens the log file and records its current size. Callers must hold rf.mu
+// or have exclusive access.
+func (rf *rotatingFile) open() error {
+	file, err := os.OpenFile(rf.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat log file: %w", err)
+	}
+
+	rf.file = file
+	rf.size = info.Size()
+	return nil
+}
+
+// rotate shifts existing backups up by one, moves the current file to path.1,
+// and opens a fresh file. If the file cannot be moved aside it is reopened and
+// appended to instead. Failures are reported on stderr once, until a rotation
+// succeeds again. Callers must hold rf.mu.
+func (rf *rotatingFile) rotate() {
+	// The descriptor is released even when Close reports an error
+	rf.file.Close()
+	rf.file = nil
+
+	err := rf.moveAside()
+	if err == nil {
+		rf.removeExpired()
+	}
+	if openErr := rf.open(); openErr != nil {
+		err = errors.Join(err, openErr)
+	} else if err != nil {
+		// Try again once another maxSize bytes have been appended
+		rf.size = 0
+	}
+
+	if err == nil {
+		rf.failing = false
+		return
+	}
+	if !rf.failing {
+		rf.failing = true
+		fmt.Fprintf(os.Stderr, "Log rotation of %s failed: %v\n", rf.path, err)
+	}
+}
+
+// moveAside shifts existing backups up by one and moves the current file to
+// path.1, or removes it when no backups are kept
+func (rf *rotatingFile) moveAside() error {
+	if rf.maxBackups > 0 {
+		os.Remove(fmt.Sprintf("%s.%d", rf.path, rf.maxBackups))
+		for i := rf.maxBackups - 1; i >= 1; i-- {
+			os.Rename(fmt.Sprintf("%s.%d", rf.path, i), fmt.Sprintf("%s.%d", rf.path, i+1))
+		}
+		if err := os.Rename(rf.path, rf.path+".1"); err != nil {
+			return fmt.Errorf("failed to rotate log file: %w", err)
+		}
+	} else if err := os.Remove(rf.path); err != nil {
+		return fmt.Errorf("failed to rotate log file: %w", err)
+	}
+	return nil
+}
+
+// removeExpired deletes backups last written more than maxAge ago
+func (rf *rotatingFile) removeExpired() {
+	if rf.maxAge <= 0 {
+		return
+	}
+	cutoff := time.Now().Add(-rf.maxAge)
+	for i := 1; i <= rf.maxBackups; i++ {
+		backup := fmt.Sprintf("%s.%d", rf.path, i)
+		if info, err := os.Stat(backup); err == nil && info.ModTime().Before(cutoff) {
+			os.Remove(backup)
+		}
+	}
+}
+
+// samePath reports whether a and b name the same file, comparing absolute paths
+func samePath(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	if errA != nil || errB != nil {
+		return filepath.Clean(a) == filepath.Clean(b)
+	}
+	return absA == absB
+}
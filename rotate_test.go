@@ -0,0 +1,70 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRotatingFileRotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	rf, err := openRotatingFile(path, 1, 2, 0)
+	if err != nil {
+		t.Fatalf("openRotatingFile: %v", err)
+	}
+	defer rf.Close()
+	rf.maxSize = 10
+
+	for _, line := range []string{"first\n", "second\n", "third\n"} {
+		if _, err := rf.Write([]byte(line)); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	for suffix, want := range map[string]string{"": "third\n", ".1": "second\n", ".2": "first\n"} {
+		if data, _ := os.ReadFile(path + suffix); string(data) != want {
+			t.Errorf("%s holds %q, want %q", filepath.Base(path+suffix), data, want)
+		}
+	}
+}
+
+func TestRotatingFileSurvivesFailedRotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+
+	// A non-empty directory where the backup should go makes the rename fail
+	if err := os.MkdirAll(filepath.Join(path+".1", "blocker"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	stderr, err := os.Create(filepath.Join(dir, "stderr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	realStderr := os.Stderr
+	os.Stderr = stderr
+	defer func() { os.Stderr = realStderr }()
+
+	rf, err := openRotatingFile(path, 1, 1, 0)
+	if err != nil {
+		t.Fatalf("openRotatingFile: %v", err)
+	}
+	defer rf.Close()
+	rf.maxSize = 10
+
+	lines := []string{"first\n", "second\n", "third\n", "fourth\n"}
+	for _, line := range lines {
+		if _, err := rf.Write([]byte(line)); err != nil {
+			t.Fatalf("Write after a failed rotation: %v", err)
+		}
+	}
+
+	// Every line is still appended to the original file
+	if data, _ := os.ReadFile(path); string(data) != strings.Join(lines, "") {
+		t.Errorf("log holds %q, want every line", data)
+	}
+	report, _ := os.ReadFile(stderr.Name())
+	if n := strings.Count(string(report), "Log rotation of"); n != 1 {
+		t.Errorf("rotation failure reported %d times, want once:\n%s", n, report)
+	}
+}
@@ -0,0 +1,518 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestManager builds a service manager from a config file holding extra,
+// backed by a SQLite database in a temporary directory
+func newTestManager(t *testing.T, extra string) *ServiceManager {
+	t.Helper()
+
+	dir := t.TempDir()
+	config := "database:\n  driver: sqlite\n  db_name: " + filepath.Join(dir, "test.db") + "\n" + extra
+	path := filepath.Join(dir, "config.yml")
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	sm, err := NewServiceManager(path)
+	if err != nil {
+		t.Fatalf("failed to create service manager: %v", err)
+	}
+	if !testing.Verbose() {
+		sm.logger.Base().SetOutput(io.Discard)
+	}
+	t.Cleanup(sm.cancel)
+	return sm
+}
+
+// freePort returns a TCP port that was free a moment ago
+func freePort(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to find a free port: %v", err)
+	}
+	defer listener.Close()
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	return port
+}
+
+// writeScript writes a Python script to a temporary directory and returns its path
+func writeScript(t *testing.T, source string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "server.py")
+	if err := os.WriteFile(path, []byte(source), 0o600); err != nil {
+		t.Fatalf("failed to write script: %v", err)
+	}
+	return path
+}
+
+// waitFor polls cond until it holds or timeout elapses
+func waitFor(t *testing.T, timeout time.Duration, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out after %s waiting for %s", timeout, what)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestExitCodeActionsDecode(t *testing.T) {
+	configs := map[string]string{
+		"config.yml":  "server:\n  exit_code_actions:\n    3: ignore\ndatabase:\n  driver: sqlite\n  db_name: test.db\n",
+		"config.json": `{"server": {"exit_code_actions": {"3": "ignore"}}, "database": {"driver": "sqlite", "db_name": "test.db"}}`,
+		"config.toml": "[server.exit_code_actions]\n3 = \"ignore\"\n[database]\ndriver = \"sqlite\"\ndb_name = \"test.db\"\n",
+	}
+	for name, data := range configs {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+				t.Fatalf("failed to write config: %v", err)
+			}
+			config, err := loadConfig(path)
+			if err != nil {
+				t.Fatalf("loadConfig: %v", err)
+			}
+			if got := config.Server.ExitCodeActions[3]; got != "ignore" {
+				t.Errorf("action for exit code 3 = %q, want ignore", got)
+			}
+		})
+	}
+}
+
+func TestExitCodeActionsBadKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	data := `{"server": {"exit_code_actions": {"three": "ignore"}}}`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	_, err := loadConfig(path)
+	if err == nil || !strings.Contains(err.Error(), `key "three" is not an exit code`) {
+		t.Errorf("loadConfig error = %v, want it to name the bad key", err)
+	}
+}
+
+func TestNegativeShutdownTimeoutRejected(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	data := "server:\n  shutdown_timeout: -1s\ndatabase:\n  driver: sqlite\n  db_name: test.db\n"
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	_, err := loadConfig(path)
+	if err == nil || !strings.Contains(err.Error(), "server.shutdown_timeout: -1s must be positive") {
+		t.Errorf("loadConfig error = %v, want shutdown_timeout rejected", err)
+	}
+}
+
+func TestNegativeIntervalsRejected(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	data := `server:
+  python_health_interval: -1s
+database:
+  driver: sqlite
+  db_name: test.db
+  check_interval: -1s
+logging:
+  flush_interval: -1s
+`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	_, err := loadConfig(path)
+	if err == nil {
+		t.Fatal("loadConfig accepted negative intervals")
+	}
+	for _, want := range []string{
+		"server.python_health_interval: -1s must not be negative",
+		"database.check_interval: -1s must not be negative",
+		"logging.flush_interval: -1s must not be negative",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("loadConfig error = %v, want it to report %q", err, want)
+		}
+	}
+}
+
+func TestInvalidReadyLogPatternRejected(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	data := "server:\n  ready_log_pattern: \"listening on (\"\ndatabase:\n  driver: sqlite\n  db_name: test.db\n"
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	_, err := loadConfig(path)
+	if err == nil || !strings.Contains(err.Error(), `server.ready_log_pattern: "listening on (" is not a valid regular expression`) {
+		t.Errorf("loadConfig error = %v, want ready_log_pattern rejected", err)
+	}
+}
+
+func TestStartRollsBackOnMissingScript(t *testing.T) {
+	dir := t.TempDir()
+	socket := filepath.Join(dir, "health.sock")
+	sm := newTestManager(t, "server:\n  script_path: "+filepath.Join(dir, "missing.py")+"\n  health_socket: "+socket+"\n")
+
+	err := sm.Start()
+	if err == nil || !strings.Contains(err.Error(), "python script not available") {
+		t.Fatalf("Start error = %v, want the missing script reported", err)
+	}
+
+	// Everything started before the failure has been stopped again
+	if sm.ctx.Err() == nil {
+		t.Error("manager context is still live after a failed start")
+	}
+	if sm.database() != nil {
+		t.Error("database is still open after a failed start")
+	}
+	if conn, err := net.Dial("unix", socket); err == nil {
+		conn.Close()
+		t.Error("health server is still listening after a failed start")
+	}
+}
+
+// testDriver is a database driver whose connections can be made slow to open or
+// ping, and made to fail their pings. It tracks how many connections are being
+// opened at once and how many are open.
+type testDriver struct {
+	openDelay  time.Duration
+	pingDelay  atomic.Int64
+	failPing   atomic.Bool
+	opening    atomic.Int32
+	maxOpening atomic.Int32
+	opened     atomic.Int32
+	open       atomic.Int32
+}
+
+var testDrivers atomic.Int32
+
+// useTestDriver registers a fresh testDriver and points sm's database config at it
+func useTestDriver(t *testing.T, sm *ServiceManager, openDelay time.Duration) *testDriver {
+	t.Helper()
+
+	drv := &testDriver{openDelay: openDelay}
+	name := fmt.Sprintf("testdb%d", testDrivers.Add(1))
+	sql.Register(name, drv)
+
+	updateConfig(sm, func(config *Config) {
+		config.Database.Driver = name
+		config.Database.URL = name
+	})
+	return drv
+}
+
+// updateConfig swaps in a copy of sm's config changed by update, as a reload would
+func updateConfig(sm *ServiceManager, update func(*Config)) {
+	sm.configMu.Lock()
+	defer sm.configMu.Unlock()
+	next := *sm.config
+	update(&next)
+	sm.config = &next
+}
+
+func (d *testDriver) Open(string) (driver.Conn, error) {
+	n := d.opening.Add(1)
+	defer d.opening.Add(-1)
+	for {
+		peak := d.maxOpening.Load()
+		if n <= peak || d.maxOpening.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+
+	time.Sleep(d.openDelay)
+	d.opened.Add(1)
+	d.open.Add(1)
+	return &testConn{driver: d}, nil
+}
+
+type testConn struct {
+	driver *testDriver
+	closed sync.Once
+}
+
+func (c *testConn) Ping(context.Context) error {
+	time.Sleep(time.Duration(c.driver.pingDelay.Load()))
+	if c.driver.failPing.Load() {
+		return errors.New("ping failed")
+	}
+	return nil
+}
+
+func (c *testConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c *testConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c *testConn) Close() error {
+	c.closed.Do(func() { c.driver.open.Add(-1) })
+	return nil
+}
+
+func TestDatabaseReconnectsDoNotOverlap(t *testing.T) {
+	sm := newTestManager(t, "")
+	drv := useTestDriver(t, sm, 100*time.Millisecond)
+	updateConfig(sm, func(config *Config) {
+		config.Database.CheckInterval = 10 * time.Millisecond
+		config.Database.MaxRetries = 2
+		config.Database.InitialBackoff = 10 * time.Millisecond
+		config.Database.MaxBackoff = 10 * time.Millisecond
+	})
+	if err := sm.initDatabase(context.Background()); err != nil {
+		t.Fatalf("initDatabase: %v", err)
+	}
+	t.Cleanup(sm.closeDatabase)
+
+	// Every check now fails and reconnects, each reconnect outlasting the interval
+	drv.failPing.Store(true)
+	sm.wg.Add(1)
+	go sm.runDatabaseMonitor()
+
+	// Checks started from elsewhere meanwhile are skipped rather than stacked
+	done := make(chan struct{})
+	var checkers sync.WaitGroup
+	for range 4 {
+		checkers.Add(1)
+		go func() {
+			defer checkers.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					sm.checkDatabaseHealth()
+					time.Sleep(5 * time.Millisecond)
+				}
+			}
+		}()
+	}
+
+	time.Sleep(time.Second)
+	close(done)
+	checkers.Wait()
+	sm.cancel()
+	sm.wg.Wait()
+
+	if peak := drv.maxOpening.Load(); peak != 1 {
+		t.Errorf("%d connection attempts overlapped, want 1 at a time", peak)
+	}
+	if opened := drv.opened.Load(); opened < 3 {
+		t.Errorf("only %d connections were opened, want repeated reconnects", opened)
+	}
+}
+
+func TestStartupQuietPeriod(t *testing.T) {
+	sm := newTestManager(t, "")
+	sm.startedAt = time.Now()
+	if !sm.inQuietPeriod() {
+		t.Error("not in the default quiet period right after startup")
+	}
+
+	sm = newTestManager(t, "server:\n  startup_quiet_period: -1s\n")
+	sm.startedAt = time.Now()
+	if sm.inQuietPeriod() {
+		t.Error("in the quiet period with it turned off")
+	}
+}
+
+func TestReconnectClosesReplacedConnections(t *testing.T) {
+	sm := newTestManager(t, "")
+	drv := useTestDriver(t, sm, 0)
+	if err := sm.initDatabase(context.Background()); err != nil {
+		t.Fatalf("initDatabase: %v", err)
+	}
+	t.Cleanup(sm.closeDatabase)
+
+	for range 20 {
+		if err := sm.reconnectDatabase(context.Background()); err != nil {
+			t.Fatalf("reconnectDatabase: %v", err)
+		}
+		if _, err := sm.pingDatabase(context.Background()); err != nil {
+			t.Fatalf("pingDatabase: %v", err)
+		}
+	}
+	if open := drv.open.Load(); open != 1 {
+		t.Errorf("%d connections open after 20 reconnects, want 1", open)
+	}
+}
+
+func TestReconnectDoesNotLeakFileDescriptors(t *testing.T) {
+	if _, err := os.ReadDir("/proc/self/fd"); err != nil {
+		t.Skip("no /proc/self/fd to count file descriptors with")
+	}
+	countFDs := func() int {
+		fds, _ := os.ReadDir("/proc/self/fd")
+		return len(fds)
+	}
+
+	sm := newTestManager(t, "")
+	if err := sm.initDatabase(context.Background()); err != nil {
+		t.Fatalf("initDatabase: %v", err)
+	}
+	t.Cleanup(sm.closeDatabase)
+	if _, err := sm.pingDatabase(context.Background()); err != nil {
+		t.Fatalf("pingDatabase: %v", err)
+	}
+
+	before := countFDs()
+	for range 20 {
+		if err := sm.reconnectDatabase(context.Background()); err != nil {
+			t.Fatalf("reconnectDatabase: %v", err)
+		}
+		if _, err := sm.pingDatabase(context.Background()); err != nil {
+			t.Fatalf("pingDatabase: %v", err)
+		}
+	}
+	if after := countFDs(); after > before {
+		t.Errorf("%d file descriptors open after 20 reconnects, %d before", after, before)
+	}
+}
+
+func TestReconnectWaitsForInFlightPing(t *testing.T) {
+	sm := newTestManager(t, "")
+	drv := useTestDriver(t, sm, 0)
+	if err := sm.initDatabase(context.Background()); err != nil {
+		t.Fatalf("initDatabase: %v", err)
+	}
+	t.Cleanup(sm.closeDatabase)
+	old := sm.database()
+
+	// A slow ping is still using the handle when a reconnect replaces it
+	drv.pingDelay.Store(int64(300 * time.Millisecond))
+	pinged := make(chan error, 1)
+	go func() {
+		_, err := sm.pingDatabase(context.Background())
+		pinged <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	drv.pingDelay.Store(0)
+	if err := sm.initDatabase(context.Background()); err != nil {
+		t.Fatalf("initDatabase: %v", err)
+	}
+
+	if err := old.Ping(); err != nil {
+		t.Errorf("replaced handle was closed while a ping was using it: %v", err)
+	}
+	if err := <-pinged; err != nil {
+		t.Errorf("in-flight ping failed across the reconnect: %v", err)
+	}
+	waitFor(t, time.Second, "the replaced handle to be closed", func() bool { return old.Ping() != nil })
+}
+
+// healthyScript serves 200 on every path and reports ready on stdout
+const healthyScript = `
+import http.server, os
+class Handler(http.server.BaseHTTPRequestHandler):
+    def do_GET(self):
+        self.send_response(200)
+        self.end_headers()
+        self.wfile.write(b"ok")
+    def log_message(self, *args):
+        pass
+server = http.server.HTTPServer(("127.0.0.1", int(os.environ["PORT"])), Handler)
+print("READY", flush=True)
+server.serve_forever()
+`
+
+// TestHealthDuringReconnects hammers the health endpoints while the database is
+// reconnected over and over. It is meant to be run with -race.
+func TestHealthDuringReconnects(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "health.sock")
+	sm := newTestManager(t, fmt.Sprintf(`server:
+  port: "%s"
+  script_path: %s
+  python_candidates: [no-such-python, python3]
+  ready_log_pattern: READY
+  python_health_host: 127.0.0.1
+  health_socket: %s
+  admin_token: secret
+`, freePort(t), writeScript(t, healthyScript), socket))
+	if err := sm.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+		},
+	}}
+	get := func(path string) (int, error) {
+		req, _ := http.NewRequest(http.MethodGet, "http://health"+path, nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		resp, err := client.Do(req)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", path, err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		return resp.StatusCode, nil
+	}
+
+	// Two /health clients so that their database pings overlap
+	done := make(chan struct{})
+	errs := make(chan error, 8)
+	var hammer sync.WaitGroup
+	for _, path := range []string{"/health", "/health", "/ready", "/config", "/metrics"} {
+		hammer.Add(1)
+		go func() {
+			defer hammer.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				// /ready may report 503 while the database is swapped, so only
+				// transport errors count for it
+				status, err := get(path)
+				if err == nil && status != http.StatusOK && path != "/ready" {
+					err = fmt.Errorf("%s returned %d", path, status)
+				}
+				if err != nil {
+					select {
+					case errs <- err:
+					default:
+					}
+					return
+				}
+			}
+		}()
+	}
+
+	for range 20 {
+		if err := sm.reconnectDatabase(context.Background()); err != nil {
+			t.Errorf("reconnectDatabase: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	close(done)
+	hammer.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	sm.cancel()
+	sm.Wait()
+}
@@ -0,0 +1,149 @@
+//go:build linux
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// running reports whether pid is running, counting zombies as exited
+func running(pid int) bool {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return false
+	}
+	stat := string(data)
+	fields := strings.Fields(stat[strings.LastIndexByte(stat, ')')+1:])
+	return len(fields) > 0 && fields[0] != "Z"
+}
+
+// startWorker runs script as the only Python worker with the given extra server
+// settings, returning once it is ready
+func startWorker(t *testing.T, script, settings string) *ServiceManager {
+	t.Helper()
+
+	sm := newTestManager(t, fmt.Sprintf(`server:
+  port: "%s"
+  script_path: %s
+  ready_log_pattern: READY
+%s`, freePort(t), writeScript(t, script), settings))
+
+	sm.wg.Add(1)
+	go sm.runWebServer()
+	t.Cleanup(func() {
+		sm.cancel()
+		sm.wg.Wait()
+	})
+	waitFor(t, 10*time.Second, "the worker to become ready", sm.workers[0].isReady)
+	return sm
+}
+
+// stopWorkers shuts the manager down and returns how long the workers took to stop
+func stopWorkers(sm *ServiceManager) time.Duration {
+	start := time.Now()
+	sm.cancel()
+	sm.wg.Wait()
+	return time.Since(start)
+}
+
+func TestShutdownKillsAfterTimeout(t *testing.T) {
+	sm := startWorker(t, `
+import signal, time
+signal.signal(signal.SIGTERM, signal.SIG_IGN)
+print("READY", flush=True)
+time.sleep(60)
+`, "  shutdown_timeout: 500ms\n")
+	pid := sm.workers[0].pid()
+
+	elapsed := stopWorkers(sm)
+	if elapsed < 500*time.Millisecond || elapsed > 500*time.Millisecond+forceKillGrace {
+		t.Errorf("shutdown took %s, want the 500ms shutdown timeout", elapsed)
+	}
+	waitFor(t, time.Second, "Python to be killed", func() bool { return !running(pid) })
+}
+
+func TestShutdownTimeoutShorterThanEscalation(t *testing.T) {
+	sm := startWorker(t, `
+import signal, time
+signal.signal(signal.SIGINT, signal.SIG_IGN)
+print("READY", flush=True)
+time.sleep(60)
+`, "  shutdown_timeout: 2ns\n  shutdown_escalation: [SIGINT, SIGTERM, SIGQUIT]\n")
+	pid := sm.workers[0].pid()
+
+	// A timeout too short to step through the sequence goes straight to the kill
+	if elapsed := stopWorkers(sm); elapsed > forceKillGrace {
+		t.Errorf("shutdown took %s, want an immediate kill", elapsed)
+	}
+	waitFor(t, time.Second, "Python to be killed", func() bool { return !running(pid) })
+}
+
+// readPid reads a PID the test script wrote to path
+func readPid(t *testing.T, path string) int {
+	t.Helper()
+
+	var pid int
+	waitFor(t, 5*time.Second, "the child PID", func() bool {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return false
+		}
+		pid, err = strconv.Atoi(strings.TrimSpace(string(data)))
+		return err == nil
+	})
+	return pid
+}
+
+// groupScript starts a child that handles SIGTERM with the signal module
+// handler named by the first verb and writes its PID to the file named by the
+// second. Python itself ignores SIGTERM and exits once the child does.
+const groupScript = `
+import signal, subprocess, sys
+signal.signal(signal.SIGTERM, signal.SIG_IGN)
+child = subprocess.Popen([sys.executable, "-c", """
+import os, signal, time
+signal.signal(signal.SIGTERM, signal.%s)
+with open(%q, "w") as f:
+    f.write(str(os.getpid()))
+time.sleep(60)
+"""])
+print("READY", flush=True)
+child.wait()
+`
+
+// startGroup runs groupScript in its own process group with a 3s shutdown
+// timeout, returning the manager and the child's PID
+func startGroup(t *testing.T, childHandler string) (*ServiceManager, int) {
+	t.Helper()
+
+	pidFile := filepath.Join(t.TempDir(), "child.pid")
+	sm := startWorker(t, fmt.Sprintf(groupScript, childHandler, pidFile),
+		"  new_process_group: true\n  shutdown_timeout: 3s\n")
+	return sm, readPid(t, pidFile)
+}
+
+func TestProcessGroupReceivesShutdownSignal(t *testing.T) {
+	sm, child := startGroup(t, "SIG_DFL")
+
+	// Only the child acts on SIGTERM, so a prompt exit means the group got it
+	if elapsed := stopWorkers(sm); elapsed > 1500*time.Millisecond {
+		t.Errorf("shutdown took %s, want the child to stop on the group's SIGTERM", elapsed)
+	}
+	waitFor(t, time.Second, "the child to exit", func() bool { return !running(child) })
+}
+
+func TestProcessGroupForcedKill(t *testing.T) {
+	sm, child := startGroup(t, "SIG_IGN")
+
+	elapsed := stopWorkers(sm)
+	if elapsed < 3*time.Second || elapsed > 3*time.Second+forceKillGrace {
+		t.Errorf("shutdown took %s, want the 3s shutdown timeout", elapsed)
+	}
+	waitFor(t, time.Second, "the child to be killed", func() bool { return !running(child) })
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+// statusWriteTimeout bounds writing one status change to the database
+const statusWriteTimeout = 2 * time.Second
+
+// statusTablePattern matches a table name, optionally schema-qualified, that is
+// safe to put in a query
+var statusTablePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// healthHistory remembers the last health of each component so that only
+// changes are written to the status table
+type healthHistory struct {
+	mu            sync.Mutex
+	last          map[string]bool
+	warnedMissing bool
+	// enabled is set by Start, so dry runs such as -check leave the table alone
+	enabled bool
+}
+
+// enable starts recording health changes
+func (h *healthHistory) enable() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.enabled = true
+}
+
+// changed records healthy as component's current health and reports whether it
+// differs from the previous one. Nothing is recorded until enable is called.
+func (h *healthHistory) changed(component string, healthy bool) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.enabled {
+		return false
+	}
+	if h.last == nil {
+		h.last = make(map[string]bool)
+	}
+	if previous, ok := h.last[component]; ok && previous == healthy {
+		return false
+	}
+	h.last[component] = healthy
+	return true
+}
+
+// warnMissingOnce reports whether the missing-table warning should be logged,
+// which is only the first time
+func (h *healthHistory) warnMissingOnce() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.warnedMissing {
+		return false
+	}
+	h.warnedMissing = true
+	return true
+}
+
+// setWorkerReady updates a worker's readiness and records the change
+func (sm *ServiceManager) setWorkerReady(w *pythonWorker, ready bool) {
+	w.setReady(ready)
+	sm.recordStatus(w.label, ready)
+}
+
+// recordStatus writes a row to the status table when component's health has
+// changed since it was last recorded. A missing table is warned about once and
+// otherwise skipped.
+func (sm *ServiceManager) recordStatus(component string, healthy bool) {
+	table := sm.cfg().Database.StatusTable
+	if table == "" || !sm.history.changed(component, healthy) {
+		return
+	}
+	// Components going down as the manager stops are not a health change
+	db, release := sm.acquireDatabase()
+	defer release()
+	if sm.ctx.Err() != nil || db == nil {
+		return
+	}
+
+	status := "unhealthy"
+	if healthy {
+		status = "healthy"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), statusWriteTimeout)
+	defer cancel()
+
+	query := fmt.Sprintf("INSERT INTO %s (recorded_at, component, status) VALUES (%s)",
+		table, placeholders(sm.cfg().Database.Driver, 3))
+	if _, err := db.ExecContext(ctx, query, time.Now().UTC(), component, status); err != nil {
+		if isMissingTable(err) {
+			if sm.history.warnMissingOnce() {
+				sm.logger.Warnf("Status table %s does not exist, status changes are not recorded", table)
+			}
+			return
+		}
+		sm.logger.Warnf("Failed to record %s status %s: %v", component, status, err)
+		return
+	}
+	sm.logger.Debugf("Recorded %s status %s", component, status)
+}
+
+// placeholders returns n comma-separated query placeholders for the driver
+func placeholders(driver string, n int) string {
+	marks := make([]string, n)
+	for i := range marks {
+		if driver == "postgres" {
+			marks[i] = fmt.Sprintf("$%d", i+1)
+		} else {
+			marks[i] = "?"
+		}
+	}
+	return strings.Join(marks, ", ")
+}
+
+// isMissingTable reports whether err means the table does not exist
+func isMissingTable(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "42P01" // undefined_table
+	}
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == 1146 // ER_NO_SUCH_TABLE
+	}
+	return strings.Contains(err.Error(), "no such table") // sqlite
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"net/http"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// goroutineGroup is a count of goroutines sharing the same top function
+type goroutineGroup struct {
+	Function string `json:"function"`
+	Count    int    `json:"count"`
+}
+
+// threadsHandler returns the goroutine count grouped by top function
+func (sm *ServiceManager) threadsHandler(w http.ResponseWriter, r *http.Request) {
+	if !sm.authorized(r) {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&buf, 1); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"total":  runtime.NumGoroutine(),
+		"groups": summarizeGoroutines(buf.Bytes()),
+	})
+}
+
+// summarizeGoroutines parses a debug=1 goroutine profile into counts per top
+// function. Runtime frames such as gopark are skipped so goroutines are grouped
+// by the code that is actually waiting.
+func summarizeGoroutines(profile []byte) []goroutineGroup {
+	counts := make(map[string]int)
+
+	var count int
+	var first, top string
+	flush := func() {
+		if count == 0 {
+			return
+		}
+		if top == "" {
+			top = first
+		}
+		if top == "" {
+			top = "unknown"
+		}
+		counts[top] += count
+		count, first, top = 0, "", ""
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(profile))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.Contains(line, " @ "):
+			flush()
+			count, _ = strconv.Atoi(strings.Fields(line)[0])
+		case strings.HasPrefix(line, "#\t") && count > 0 && top == "":
+			fields := strings.Split(line, "\t")
+			if len(fields) < 3 {
+				continue
+			}
+			fn, _, _ := strings.Cut(fields[2], "+0x")
+			if first == "" {
+				first = fn
+			}
+			if !strings.HasPrefix(fn, "runtime.") {
+				top = fn
+			}
+		case line == "":
+			flush()
+		}
+	}
+	flush()
+
+	groups := make([]goroutineGroup, 0, len(counts))
+	for fn, n := range counts {
+		groups = append(groups, goroutineGroup{Function: fn, Count: n})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Count != groups[j].Count {
+			return groups[i].Count > groups[j].Count
+		}
+		return groups[i].Function < groups[j].Function
+	})
+	return groups
+}
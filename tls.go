@@ -0,0 +1,57 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+)
+
+// parseTLSVersion converts a version such as "1.2" to its crypto/tls constant.
+// Versions below 1.2 are rejected.
+func parseTLSVersion(version string) (uint16, error) {
+	switch version {
+	case "1.0", "1.1":
+		return 0, fmt.Errorf("TLS %s is insecure, use 1.2 or 1.3", version)
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unknown TLS version: %s", version)
+	}
+}
+
+// parseCipherSuites converts cipher suite names to their IDs. Only suites Go
+// considers secure are accepted.
+func parseCipherSuites(names []string) ([]uint16, error) {
+	known := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		known[suite.Name] = suite.ID
+	}
+
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := known[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown or insecure cipher suite: %s", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// healthTLSConfig builds the TLS configuration for the health server's HTTPS listener.
+// An empty cipher suite list keeps Go's secure defaults.
+func (sm *ServiceManager) healthTLSConfig() (*tls.Config, error) {
+	minVersion, err := parseTLSVersion(sm.cfg().Server.TLSMinVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &tls.Config{MinVersion: minVersion}
+	if len(sm.cfg().Server.TLSCipherSuites) > 0 {
+		if config.CipherSuites, err = parseCipherSuites(sm.cfg().Server.TLSCipherSuites); err != nil {
+			return nil, err
+		}
+	}
+	return config, nil
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"crypto/tls"
+	"testing"
+)
+
+func TestParseTLSVersion(t *testing.T) {
+	for version, want := range map[string]uint16{"1.2": tls.VersionTLS12, "1.3": tls.VersionTLS13} {
+		got, err := parseTLSVersion(version)
+		if err != nil || got != want {
+			t.Errorf("parseTLSVersion(%q) = %#x, %v, want %#x", version, got, err, want)
+		}
+	}
+	for _, version := range []string{"1.0", "1.1", "2.0", ""} {
+		if _, err := parseTLSVersion(version); err == nil {
+			t.Errorf("parseTLSVersion(%q) accepted a version below 1.2 or unknown", version)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracingShutdownTimeout bounds how long pending spans may take to export on shutdown
+const tracingShutdownTimeout = 5 * time.Second
+
+// initTracing sets up span export to the configured OTLP endpoint. Without an
+// endpoint the tracer is a no-op.
+func (sm *ServiceManager) initTracing() error {
+	cfg := sm.cfg().Tracing
+	if cfg.Endpoint == "" {
+		return nil
+	}
+
+	// The endpoint may be given without the standard traces path
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil {
+		return fmt.Errorf("failed to parse tracing endpoint: %w", err)
+	}
+	if endpoint.Path == "" || endpoint.Path == "/" {
+		endpoint.Path = "/v1/traces"
+	}
+
+	exporter, err := otlptracehttp.New(context.Background(), otlptracehttp.WithEndpointURL(endpoint.String()))
+	if err != nil {
+		return fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+
+	sm.tracerProvider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", cfg.ServiceName),
+			attribute.String("service.version", version),
+		)),
+	)
+	sm.tracer = sm.tracerProvider.Tracer("friend-finder/service-manager")
+	sm.logger.Infof("Exporting traces to %s", endpoint)
+	return nil
+}
+
+// shutdownTracing flushes pending spans to the exporter
+func (sm *ServiceManager) shutdownTracing() {
+	if sm.tracerProvider == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+	defer cancel()
+	if err := sm.tracerProvider.Shutdown(ctx); err != nil {
+		sm.logger.Warnf("Failed to flush traces: %v", err)
+	}
+}
+
+// traceEnv returns TRACEPARENT (and TRACESTATE, if any) for ctx's span so a child
+// process can continue the trace. It is empty when tracing is disabled.
+func (sm *ServiceManager) traceEnv(ctx context.Context) []string {
+	if sm.tracerProvider == nil {
+		return nil
+	}
+
+	carrier := propagation.MapCarrier{}
+	propagation.TraceContext{}.Inject(ctx, carrier)
+
+	var env []string
+	if traceparent := carrier.Get("traceparent"); traceparent != "" {
+		env = append(env, "TRACEPARENT="+traceparent)
+	}
+	if tracestate := carrier.Get("tracestate"); tracestate != "" {
+		env = append(env, "TRACESTATE="+tracestate)
+	}
+	return env
+}
+
+// endSpan records err, if any, on span and ends it
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce is how long the watched files must be quiet before a change is
+// acted on, so an editor saving in several steps causes one restart
+const watchDebounce = 500 * time.Millisecond
+
+// runFileWatcher watches the config file and the Python script. After a change it
+// reloads the config if that changed, then restarts the Python workers.
+func (sm *ServiceManager) runFileWatcher() {
+	defer sm.wg.Done()
+	defer sm.recoverFromPanic("file watcher", func() {
+		sm.wg.Add(1)
+		go sm.runFileWatcher()
+	})
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		sm.logger.Errorf("Failed to start file watcher: %v", err)
+		return
+	}
+	defer watcher.Close()
+
+	configFile, err := filepath.Abs(sm.configPath)
+	if err != nil {
+		sm.logger.Errorf("Failed to resolve config path: %v", err)
+		return
+	}
+	script, err := filepath.Abs(scriptPath(sm.cfg()))
+	if err != nil {
+		sm.logger.Errorf("Failed to resolve script path: %v", err)
+		return
+	}
+
+	// Editors often replace a file rather than write to it, which a watch on the
+	// file itself would not survive, so watch the directories instead
+	for _, dir := range []string{filepath.Dir(configFile), filepath.Dir(script)} {
+		if err := watcher.Add(dir); err != nil {
+			sm.logger.Errorf("Failed to watch %s: %v", dir, err)
+			return
+		}
+	}
+	sm.logger.Infof("Watching %s and %s for changes", configFile, script)
+
+	debounce := time.NewTimer(watchDebounce)
+	debounce.Stop()
+	defer debounce.Stop()
+	var configChanged, scriptChanged bool
+
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+			switch filepath.Clean(event.Name) {
+			case configFile:
+				configChanged = true
+			case script:
+				scriptChanged = true
+			default:
+				continue
+			}
+			debounce.Reset(watchDebounce)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			sm.logger.Warnf("File watcher error: %v", err)
+		case <-debounce.C:
+			sm.applyFileChanges(configChanged, scriptChanged)
+			configChanged, scriptChanged = false, false
+		case <-sm.ctx.Done():
+			sm.logger.Info("File watcher shutting down...")
+			return
+		}
+	}
+}
+
+// applyFileChanges reloads a changed config and restarts Python. A config that
+// fails to load leaves Python running as it is.
+func (sm *ServiceManager) applyFileChanges(configChanged, scriptChanged bool) {
+	if configChanged {
+		sm.logger.Infof("Config file changed, reloading %s", sm.configPath)
+		restarting, err := sm.reloadConfig()
+		if err != nil {
+			sm.logger.Errorf("Config reload failed, keeping current config: %v", err)
+			return
+		}
+		// The reload already restarts Python when the command changed
+		if restarting {
+			return
+		}
+	}
+	if scriptChanged {
+		sm.logger.Info("Python script changed, restarting Python")
+	} else {
+		sm.logger.Info("Restarting Python for the config change")
+	}
+	if err := sm.restartWorkers(sm.ctx); err != nil {
+		sm.logger.Errorf("Python restart after file change failed: %v", err)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import "time"
+
+// runPythonHealthMonitor polls each ready worker's health endpoint and restarts a
+// worker after UnhealthyThreshold consecutive failures, catching processes that
+// are running but no longer serving. Those restarts count as crashes toward
+// the restart policy.
+func (sm *ServiceManager) runPythonHealthMonitor() {
+	defer sm.wg.Done()
+	defer sm.recoverFromPanic("python health monitor", func() {
+		sm.wg.Add(1)
+		go sm.runPythonHealthMonitor()
+	})
+
+	cfg := sm.cfg().Server
+	sm.logger.Infof("Starting Python health monitor (every %s, restart after %d failures)",
+		cfg.PythonHealthInterval, cfg.UnhealthyThreshold)
+
+	ticker := time.NewTicker(cfg.PythonHealthInterval)
+	defer ticker.Stop()
+
+	failures := make([]int, len(sm.workers))
+	for {
+		select {
+		case <-ticker.C:
+			threshold := cfg.UnhealthyThreshold
+			// Slow starters are left alone while the manager settles after startup
+			if sm.inQuietPeriod() {
+				clear(failures)
+				continue
+			}
+			for i, w := range sm.workers {
+				// Workers still starting up are covered by the ready timeout
+				if !w.running() || !w.isReady() {
+					failures[i] = 0
+					continue
+				}
+
+				if sm.probePythonHealth(sm.ctx, w) {
+					failures[i] = 0
+					continue
+				}
+				if sm.ctx.Err() != nil {
+					return
+				}
+
+				failures[i]++
+				sm.logger.Warnf("%s health check failed (%d of %d)", w.label, failures[i], threshold)
+				if failures[i] < threshold {
+					continue
+				}
+
+				failures[i] = 0
+				sm.logger.Errorf("%s failed %d consecutive health checks, restarting it", w.label, threshold)
+				sm.setWorkerReady(w, false)
+				if err := w.stopUnhealthy(); err != nil {
+					sm.logger.Warnf("Failed to restart %s: %v", w.label, err)
+				}
+			}
+		case <-sm.ctx.Done():
+			sm.logger.Info("Python health monitor shutting down...")
+			return
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// unhealthyScript starts serving, reports ready on stdout and then fails every
+// health check
+const unhealthyScript = `
+import http.server, os
+class Handler(http.server.BaseHTTPRequestHandler):
+    def do_GET(self):
+        self.send_response(500)
+        self.end_headers()
+    def log_message(self, *args):
+        pass
+server = http.server.HTTPServer(("127.0.0.1", int(os.environ["PORT"])), Handler)
+print("READY", flush=True)
+server.serve_forever()
+`
+
+// startWatchdog runs an unhealthy Python worker under the watchdog
+func startWatchdog(t *testing.T, quietPeriod time.Duration) *ServiceManager {
+	t.Helper()
+
+	sm := newTestManager(t, fmt.Sprintf(`server:
+  port: "%s"
+  script_path: %s
+  ready_log_pattern: READY
+  python_health_host: 127.0.0.1
+  unhealthy_threshold: 1
+  python_health_interval: 100ms
+  startup_quiet_period: %s
+  shutdown_timeout: 2s
+  restart_policy:
+    max_restarts: 100
+    initial_backoff: 10ms
+`, freePort(t), writeScript(t, unhealthyScript), quietPeriod))
+	sm.startedAt = time.Now()
+
+	sm.wg.Add(2)
+	go sm.runWebServer()
+	go sm.runPythonHealthMonitor()
+	t.Cleanup(func() {
+		sm.cancel()
+		sm.wg.Wait()
+	})
+	return sm
+}
+
+func (sm *ServiceManager) restartCount() int {
+	sm.statsMu.Lock()
+	defer sm.statsMu.Unlock()
+	return sm.pythonRestarts
+}
+
+func TestWatchdogRestartsUnhealthyWorker(t *testing.T) {
+	sm := startWatchdog(t, -time.Second)
+
+	w := sm.workers[0]
+	waitFor(t, 10*time.Second, "the worker to become ready", w.isReady)
+	pid := w.pid()
+
+	waitFor(t, 10*time.Second, "the watchdog to replace the worker", func() bool {
+		got := w.pid()
+		return got != 0 && got != pid
+	})
+}
+
+func TestWatchdogRestartCountsAsCrash(t *testing.T) {
+	sm := startWatchdog(t, time.Millisecond)
+
+	waitFor(t, 10*time.Second, "a watchdog restart", func() bool { return sm.restartCount() > 0 })
+}
+
+func TestWatchdogQuietPeriod(t *testing.T) {
+	sm := startWatchdog(t, time.Minute)
+
+	w := sm.workers[0]
+	waitFor(t, 10*time.Second, "the worker to become ready", w.isReady)
+	pid := w.pid()
+
+	// Several health polls fail during the quiet period without a restart
+	time.Sleep(time.Second)
+	if n := sm.restartCount(); n != 0 {
+		t.Errorf("worker restarted %d times during the quiet period, want 0", n)
+	}
+	if got := w.pid(); got != pid {
+		t.Errorf("worker PID changed from %d to %d during the quiet period", pid, got)
+	}
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// pythonWorker is one Python server process, restarted independently of the others
+type pythonWorker struct {
+	index int
+	port  string
+	// label names the worker in log output, e.g. "Python server" or "Python worker 1".
+	// Workers are only numbered when there is more than one.
+	label    string
+	numbered bool
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu    sync.RWMutex
+	cmd   *exec.Cmd
+	ready bool
+	// restarting is set from a crash or requested restart until the replacement
+	// process reports ready
+	restarting bool
+	// stderr is the output of the latest process, kept after it exits for triage
+	stderr *logWriter
+	// stop ends the running process without counting it as a crash
+	stop context.CancelFunc
+	// restartDone, while a requested restart is in progress, receives whether the
+	// replacement process became ready
+	restartDone chan error
+	// unhealthy is set when the watchdog stops the process, so its exit counts
+	// as a crash rather than a requested restart
+	unhealthy bool
+}
+
+// newPythonWorkers creates the configured number of workers on consecutive ports
+// starting at the server port
+func (sm *ServiceManager) newPythonWorkers() ([]*pythonWorker, error) {
+	cfg := sm.cfg()
+	basePort, err := strconv.Atoi(cfg.Server.Port)
+	if err != nil {
+		return nil, fmt.Errorf("invalid server port: %w", err)
+	}
+
+	workers := make([]*pythonWorker, cfg.Server.Workers)
+	for i := range workers {
+		ctx, cancel := context.WithCancel(sm.ctx)
+		workers[i] = &pythonWorker{
+			index:  i,
+			port:   strconv.Itoa(basePort + i),
+			label:  "Python server",
+			ctx:    ctx,
+			cancel: cancel,
+		}
+		if len(workers) > 1 {
+			workers[i].label = fmt.Sprintf("Python worker %d", i)
+			workers[i].numbered = true
+		}
+	}
+	return workers, nil
+}
+
+// logPrefix returns the prefix for one of the worker's output streams
+func (w *pythonWorker) logPrefix(stream string) string {
+	if !w.numbered {
+		return fmt.Sprintf("[PYTHON-%s]", stream)
+	}
+	return fmt.Sprintf("[PYTHON-%d-%s]", w.index, stream)
+}
+
+// pidFile returns the worker's PID file next to the manager's: service.pid ->
+// service.python.pid, or service.python.1.pid when running several workers
+func (w *pythonWorker) pidFile(managerPidFile string) string {
+	ext := filepath.Ext(managerPidFile)
+	name := strings.TrimSuffix(managerPidFile, ext) + ".python"
+	if w.numbered {
+		name += "." + strconv.Itoa(w.index)
+	}
+	return name + ext
+}
+
+// setCmd records the worker's running command and how to stop it
+func (w *pythonWorker) setCmd(cmd *exec.Cmd, stop context.CancelFunc) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.cmd = cmd
+	w.stop = stop
+	w.unhealthy = false
+}
+
+// setStderr records the stderr writer of the latest process
+func (w *pythonWorker) setStderr(stderr *logWriter) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.stderr = stderr
+}
+
+// recentStderr returns up to n of the most recent stderr lines, tagged with the
+// worker when there are several
+func (w *pythonWorker) recentStderr(n int) []string {
+	w.mu.RLock()
+	stderr := w.stderr
+	w.mu.RUnlock()
+	if stderr == nil {
+		return nil
+	}
+
+	lines := stderr.Recent()
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	if w.numbered {
+		for i, line := range lines {
+			lines[i] = fmt.Sprintf("[worker %d] %s", w.index, line)
+		}
+	}
+	return lines
+}
+
+// running reports whether the worker has a started process
+func (w *pythonWorker) running() bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.cmd != nil && w.cmd.Process != nil
+}
+
+// pid returns the PID of the worker's running process, or 0 when there is none
+func (w *pythonWorker) pid() int {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.cmd == nil || w.cmd.Process == nil {
+		return 0
+	}
+	return w.cmd.Process.Pid
+}
+
+// setReady records whether the worker has reported ready
+func (w *pythonWorker) setReady(ready bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.ready = ready
+	if ready {
+		w.restarting = false
+	}
+}
+
+// setRestarting marks the worker as being restarted until it reports ready again
+func (w *pythonWorker) setRestarting() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.restarting = true
+}
+
+// isRestarting reports whether the worker is being restarted
+func (w *pythonWorker) isRestarting() bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.restarting
+}
+
+// isReady reports whether the worker has reported ready
+func (w *pythonWorker) isReady() bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.ready
+}
+
+// healthURL returns the URL of the worker's health endpoint on the given host
+func (w *pythonWorker) healthURL(host, path string) string {
+	return "http://" + net.JoinHostPort(host, w.port) + path
+}
+
+// requestRestart stops the worker's process so its restart loop starts a fresh one
+// straight away. The returned channel receives whether the new process became ready.
+func (w *pythonWorker) requestRestart() (<-chan error, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.cmd == nil || w.stop == nil {
+		return nil, fmt.Errorf("%s is not running", w.label)
+	}
+	if w.restartDone != nil {
+		return nil, fmt.Errorf("%s is already restarting", w.label)
+	}
+
+	w.restartDone = make(chan error, 1)
+	w.stop()
+	return w.restartDone, nil
+}
+
+// stopUnhealthy stops the worker's process after failed health checks. Unlike a
+// requested restart, the exit is treated as a crash and goes through the
+// restart policy.
+func (w *pythonWorker) stopUnhealthy() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.cmd == nil || w.stop == nil {
+		return fmt.Errorf("%s is not running", w.label)
+	}
+	if w.restartDone != nil {
+		return fmt.Errorf("%s is already restarting", w.label)
+	}
+
+	w.unhealthy = true
+	w.stop()
+	return nil
+}
+
+// stoppedUnhealthy reports whether the watchdog stopped the current process
+func (w *pythonWorker) stoppedUnhealthy() bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.unhealthy
+}
+
+// takeRestart returns the channel of a requested restart, if any, for the process
+// that is about to start
+func (w *pythonWorker) takeRestart() chan error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	done := w.restartDone
+	w.restartDone = nil
+	return done
+}
+
+// finishRestart reports the outcome of a requested restart; it is a no-op when
+// the process was not started by one
+func finishRestart(done chan error, err error) {
+	if done == nil {
+		return
+	}
+	select {
+	case done <- err:
+	default:
+	}
+}
+
+// restartWorkers restarts the Python workers one at a time, waiting for each
+// replacement to become ready before moving on so the others keep serving
+func (sm *ServiceManager) restartWorkers(ctx context.Context) error {
+	for _, w := range sm.workers {
+		sm.logger.Infof("Restarting %s on request", w.label)
+
+		done, err := w.requestRestart()
+		if err != nil {
+			return err
+		}
+
+		select {
+		case err := <-done:
+			if err != nil {
+				return fmt.Errorf("%s failed to restart: %w", w.label, err)
+			}
+		case <-w.ctx.Done():
+			return errors.New("service manager is shutting down")
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	sm.logger.Info("Python restart complete")
+	return nil
+}